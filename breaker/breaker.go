@@ -0,0 +1,332 @@
+package breaker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/xyzbit/gpkg/collection"
+	"github.com/xyzbit/gpkg/threading"
+)
+
+const (
+	defaultWindow              = 10 * time.Second
+	defaultBuckets             = 40
+	defaultErrorRateThreshold  = 0.5
+	defaultMinRequests         = 20
+	defaultOpenTimeout         = 5 * time.Second
+	defaultHalfOpenMaxRequests = 5
+)
+
+// ErrServiceUnavailable 熔断器处于打开状态或半开状态的探测请求已满时返回
+var ErrServiceUnavailable = errors.New("circuit breaker is open")
+
+// State 熔断器状态
+type State int
+
+const (
+	StateClosed   State = iota // 关闭，请求正常通过
+	StateOpen                  // 打开，请求直接被拒绝
+	StateHalfOpen              // 半开，放行少量请求探测下游是否恢复
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Acceptable 判断非 nil 的 err 是否可以接受，可接受的错误不计入错误率
+type Acceptable func(err error) bool
+
+type Option func(b *Breaker)
+
+// WithName 熔断器名称，会透传给状态变更回调
+func WithName(name string) Option {
+	return func(b *Breaker) {
+		b.name = name
+	}
+}
+
+// WithWindow 统计错误率的滑动窗口，窗口被切分为 buckets 个桶，window 不足 buckets 纳秒时使用默认值
+func WithWindow(window time.Duration, buckets int) Option {
+	return func(b *Breaker) {
+		if buckets > 0 && window >= time.Duration(buckets) {
+			b.window = window
+			b.buckets = buckets
+		}
+	}
+}
+
+// WithErrorRateThreshold 触发熔断的错误率，取值 (0, 1]
+func WithErrorRateThreshold(ratio float64) Option {
+	return func(b *Breaker) {
+		if ratio > 0 && ratio <= 1 {
+			b.errorRateThreshold = ratio
+		}
+	}
+}
+
+// WithMinRequests 窗口内请求数达到 minRequests 后才计算错误率，避免请求较少时误熔断
+func WithMinRequests(minRequests int64) Option {
+	return func(b *Breaker) {
+		if minRequests > 0 {
+			b.minRequests = minRequests
+		}
+	}
+}
+
+// WithOpenTimeout 打开状态的持续时间，超时后进入半开状态
+func WithOpenTimeout(timeout time.Duration) Option {
+	return func(b *Breaker) {
+		if timeout > 0 {
+			b.openTimeout = timeout
+		}
+	}
+}
+
+// WithHalfOpenMaxRequests 半开状态下允许的探测请求数量，全部成功后关闭熔断器
+func WithHalfOpenMaxRequests(n int) Option {
+	return func(b *Breaker) {
+		if n > 0 {
+			b.halfOpenMaxRequests = n
+		}
+	}
+}
+
+// WithAcceptable 判断错误是否可接受，如业务错误、参数错误等不应触发熔断
+func WithAcceptable(acceptable Acceptable) Option {
+	return func(b *Breaker) {
+		b.acceptable = acceptable
+	}
+}
+
+// WithStateChange 状态变更回调
+func WithStateChange(fn func(name string, from, to State)) Option {
+	return func(b *Breaker) {
+		b.onStateChange = fn
+	}
+}
+
+// WithClock 自定义时钟，便于测试
+func WithClock(now func() time.Time) Option {
+	return func(b *Breaker) {
+		if now != nil {
+			b.now = now
+		}
+	}
+}
+
+// Breaker 熔断器
+//
+// 关闭状态下统计滑动窗口内的错误率，超过阈值后打开熔断器并拒绝所有请求；
+// 经过 openTimeout 后进入半开状态，放行少量探测请求，探测全部成功则关闭，任一失败则重新打开。
+type Breaker struct {
+	name                string
+	window              time.Duration
+	buckets             int
+	errorRateThreshold  float64
+	minRequests         int64
+	openTimeout         time.Duration
+	halfOpenMaxRequests int
+	acceptable          Acceptable
+	onStateChange       func(name string, from, to State)
+	now                 func() time.Time
+
+	lock       sync.Mutex
+	state      State
+	generation uint64 // 每次状态变更递增，用于丢弃旧状态下发起的请求结果
+	stat       *collection.RollingWindow
+	openedAt   time.Time
+	probing    int // 半开状态下在途的探测请求数量
+	probeSucc  int // 半开状态下成功的探测请求数量
+}
+
+func NewBreaker(opts ...Option) *Breaker {
+	b := &Breaker{
+		window:              defaultWindow,
+		buckets:             defaultBuckets,
+		errorRateThreshold:  defaultErrorRateThreshold,
+		minRequests:         defaultMinRequests,
+		openTimeout:         defaultOpenTimeout,
+		halfOpenMaxRequests: defaultHalfOpenMaxRequests,
+		now:                 time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	b.stat = collection.NewRollingWindow(b.buckets, b.window/time.Duration(b.buckets), collection.WithClock(b.now))
+	return b
+}
+
+// Name 熔断器名称
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State 熔断器当前状态
+func (b *Breaker) State() State {
+	b.lock.Lock()
+	state, change := b.currentState()
+	b.lock.Unlock()
+
+	b.notify(change)
+	return state
+}
+
+// Do 在熔断器保护下执行 fn，熔断器打开时返回 ErrServiceUnavailable
+func (b *Breaker) Do(fn func() error) error {
+	return b.do(fn, nil, b.acceptable)
+}
+
+// DoWithAcceptable 在熔断器保护下执行 fn，使用 acceptable 判断错误是否计入错误率
+func (b *Breaker) DoWithAcceptable(fn func() error, acceptable Acceptable) error {
+	return b.do(fn, nil, acceptable)
+}
+
+// DoWithFallback 在熔断器保护下执行 fn，请求被熔断器拒绝时执行 fallback
+func (b *Breaker) DoWithFallback(fn func() error, fallback func(err error) error) error {
+	return b.do(fn, fallback, b.acceptable)
+}
+
+func (b *Breaker) do(fn func() error, fallback func(err error) error, acceptable Acceptable) error {
+	generation, err := b.before()
+	if err != nil {
+		if fallback != nil {
+			return fallback(err)
+		}
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			b.after(generation, false)
+			panic(p)
+		}
+	}()
+
+	err = fn()
+	b.after(generation, err == nil || (acceptable != nil && acceptable(err)))
+	return err
+}
+
+func (b *Breaker) before() (uint64, error) {
+	b.lock.Lock()
+	state, change := b.currentState()
+	generation := b.generation
+	var err error
+	switch state {
+	case StateOpen:
+		err = ErrServiceUnavailable
+	case StateHalfOpen:
+		if b.probing >= b.halfOpenMaxRequests {
+			err = ErrServiceUnavailable
+		} else {
+			b.probing++
+		}
+	}
+	b.lock.Unlock()
+
+	b.notify(change)
+	return generation, err
+}
+
+func (b *Breaker) after(generation uint64, success bool) {
+	b.lock.Lock()
+	state, change := b.currentState()
+	if generation != b.generation {
+		b.lock.Unlock()
+		b.notify(change)
+		return
+	}
+
+	switch state {
+	case StateClosed:
+		if success {
+			b.stat.Add(0)
+		} else {
+			b.stat.Add(1)
+			if b.shouldOpen() {
+				change = b.setState(StateOpen)
+			}
+		}
+	case StateHalfOpen:
+		b.probing--
+		if !success {
+			change = b.setState(StateOpen)
+			break
+		}
+		b.probeSucc++
+		if b.probeSucc >= b.halfOpenMaxRequests {
+			change = b.setState(StateClosed)
+		}
+	}
+	b.lock.Unlock()
+
+	b.notify(change)
+}
+
+// shouldOpen 窗口内的错误率是否超过阈值，调用方需持有锁
+func (b *Breaker) shouldOpen() bool {
+	var failures float64
+	var total int64
+	b.stat.Reduce(func(bucket *collection.Bucket) {
+		failures += bucket.Sum
+		total += bucket.Count
+	})
+
+	return total >= b.minRequests && failures/float64(total) >= b.errorRateThreshold
+}
+
+// currentState 返回当前状态，打开超时后切换为半开状态，调用方需持有锁
+func (b *Breaker) currentState() (State, *stateChange) {
+	if b.state == StateOpen && !b.now().Before(b.openedAt.Add(b.openTimeout)) {
+		return StateHalfOpen, b.setState(StateHalfOpen)
+	}
+	return b.state, nil
+}
+
+// setState 切换状态并重置统计，调用方需持有锁
+func (b *Breaker) setState(state State) *stateChange {
+	if b.state == state {
+		return nil
+	}
+
+	change := &stateChange{from: b.state, to: state}
+	b.state = state
+	b.generation++
+	b.probing = 0
+	b.probeSucc = 0
+	switch state {
+	case StateClosed:
+		b.stat.Reset()
+	case StateOpen:
+		b.openedAt = b.now()
+	}
+	return change
+}
+
+// notify 在锁外执行状态变更回调，避免回调中访问熔断器导致死锁
+func (b *Breaker) notify(change *stateChange) {
+	if change == nil || b.onStateChange == nil {
+		return
+	}
+
+	threading.RunSafe(func() {
+		b.onStateChange(b.name, change.from, change.to)
+	})
+}
+
+type stateChange struct {
+	from State
+	to   State
+}
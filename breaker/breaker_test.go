@@ -0,0 +1,162 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xyzbit/gpkg/fx"
+)
+
+var errDownstream = errors.New("downstream error")
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.now = f.now.Add(d)
+}
+
+func TestState_String(t *testing.T) {
+	assert.Equal(t, "closed", StateClosed.String())
+	assert.Equal(t, "open", StateOpen.String())
+	assert.Equal(t, "half-open", StateHalfOpen.String())
+	assert.Equal(t, "unknown", State(10).String())
+}
+
+func TestBreaker_StateTransition(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	var changes []State
+	b := NewBreaker(
+		WithName("test"),
+		WithMinRequests(4),
+		WithErrorRateThreshold(0.5),
+		WithOpenTimeout(time.Second),
+		WithHalfOpenMaxRequests(2),
+		WithClock(clock.Now),
+		WithStateChange(func(name string, from, to State) {
+			assert.Equal(t, "test", name)
+			changes = append(changes, to)
+		}),
+	)
+
+	fail := func() error { return errDownstream }
+	succ := func() error { return nil }
+
+	// 请求数不足时不熔断
+	assert.ErrorIs(t, b.Do(fail), errDownstream)
+	assert.ErrorIs(t, b.Do(fail), errDownstream)
+	assert.NoError(t, b.Do(succ))
+	assert.Equal(t, StateClosed, b.State())
+
+	// 错误率达到阈值后熔断
+	assert.ErrorIs(t, b.Do(fail), errDownstream)
+	assert.Equal(t, StateOpen, b.State())
+	assert.ErrorIs(t, b.Do(succ), ErrServiceUnavailable)
+
+	// 打开超时后进入半开，探测失败重新打开
+	clock.Advance(time.Second)
+	assert.Equal(t, StateHalfOpen, b.State())
+	assert.ErrorIs(t, b.Do(fail), errDownstream)
+	assert.Equal(t, StateOpen, b.State())
+
+	// 探测全部成功后关闭
+	clock.Advance(time.Second)
+	assert.NoError(t, b.Do(succ))
+	assert.NoError(t, b.Do(succ))
+	assert.Equal(t, StateClosed, b.State())
+
+	assert.Equal(t, []State{StateOpen, StateHalfOpen, StateOpen, StateHalfOpen, StateClosed}, changes)
+}
+
+func TestBreaker_HalfOpenMaxRequests(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	b := NewBreaker(WithMinRequests(1), WithOpenTimeout(time.Second), WithHalfOpenMaxRequests(1), WithClock(clock.Now))
+
+	_ = b.Do(func() error { return errDownstream })
+	clock.Advance(time.Second)
+
+	// 探测请求在途时，其余请求被拒绝
+	err := b.Do(func() error {
+		return b.Do(func() error { return nil })
+	})
+	assert.ErrorIs(t, err, ErrServiceUnavailable)
+}
+
+func TestBreaker_Acceptable(t *testing.T) {
+	errNotFound := errors.New("not found")
+	b := NewBreaker(WithMinRequests(1), WithErrorRateThreshold(0.05), WithAcceptable(func(err error) bool {
+		return errors.Is(err, errNotFound)
+	}))
+
+	for i := 0; i < 10; i++ {
+		assert.ErrorIs(t, b.Do(func() error { return errNotFound }), errNotFound)
+	}
+	assert.Equal(t, StateClosed, b.State())
+
+	err := b.DoWithAcceptable(func() error { return errDownstream }, func(err error) bool {
+		return true
+	})
+	assert.ErrorIs(t, err, errDownstream)
+	assert.Equal(t, StateClosed, b.State())
+
+	_ = b.Do(func() error { return errDownstream })
+	assert.Equal(t, StateOpen, b.State())
+}
+
+func TestBreaker_DoWithFallback(t *testing.T) {
+	b := NewBreaker(WithMinRequests(1))
+
+	fallback := func(err error) error {
+		assert.ErrorIs(t, err, ErrServiceUnavailable)
+		return nil
+	}
+	assert.ErrorIs(t, b.DoWithFallback(func() error { return errDownstream }, fallback), errDownstream)
+	assert.NoError(t, b.DoWithFallback(func() error { return errDownstream }, fallback))
+}
+
+func TestBreaker_Panic(t *testing.T) {
+	b := NewBreaker(WithMinRequests(1))
+
+	assert.Panics(t, func() {
+		_ = b.Do(func() error { panic("panic") })
+	})
+	assert.Equal(t, StateOpen, b.State())
+}
+
+func TestBreaker_DoWithTimeout(t *testing.T) {
+	b := NewBreaker(WithMinRequests(2))
+
+	for i := 0; i < 2; i++ {
+		err := b.Do(func() error {
+			return fx.DoWithTimeout(func() error {
+				time.Sleep(50 * time.Millisecond)
+				return nil
+			}, time.Millisecond)
+		})
+		assert.ErrorIs(t, err, fx.ErrTimeout)
+	}
+	assert.Equal(t, StateOpen, b.State())
+}
+
+func TestBreaker_WindowShorterThanBuckets(t *testing.T) {
+	b := NewBreaker(WithWindow(5*time.Nanosecond, 10), WithMinRequests(1))
+	assert.Equal(t, defaultWindow, b.window)
+	assert.Equal(t, defaultBuckets, b.buckets)
+
+	assert.NotPanics(t, func() {
+		assert.ErrorIs(t, b.Do(func() error { return errDownstream }), errDownstream)
+	})
+
+	b = NewBreaker(WithWindow(10*time.Nanosecond, 10))
+	assert.Equal(t, 10*time.Nanosecond, b.window)
+	assert.NotPanics(t, func() {
+		assert.NoError(t, b.Do(func() error { return nil }))
+	})
+}
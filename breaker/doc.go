@@ -0,0 +1,2 @@
+// Package breaker 熔断器
+package breaker
@@ -0,0 +1,123 @@
+package collection
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// RollingWindowOption let callers customize the RollingWindow.
+	RollingWindowOption func(rollingWindow *RollingWindow)
+
+	// RollingWindow defines a rolling window to calculate the events in buckets with time interval.
+	RollingWindow struct {
+		lock     sync.RWMutex
+		size     int
+		buckets  []*Bucket
+		interval time.Duration
+		offset   int
+		lastTime time.Time
+		now      func() time.Time
+	}
+
+	// Bucket defines the bucket that holds sum and num of additions.
+	Bucket struct {
+		Sum   float64
+		Count int64
+	}
+)
+
+// NewRollingWindow returns a RollingWindow that with size buckets and time interval,
+// use opts to customize the RollingWindow.
+func NewRollingWindow(size int, interval time.Duration, opts ...RollingWindowOption) *RollingWindow {
+	if size < 1 {
+		panic("size must be greater than 0")
+	}
+
+	w := &RollingWindow{
+		size:     size,
+		buckets:  make([]*Bucket, size),
+		interval: interval,
+		now:      time.Now,
+	}
+	for i := range w.buckets {
+		w.buckets[i] = new(Bucket)
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.lastTime = w.now()
+	return w
+}
+
+// WithClock customizes the time source of the RollingWindow, mostly used in tests.
+func WithClock(now func() time.Time) RollingWindowOption {
+	return func(w *RollingWindow) {
+		w.now = now
+	}
+}
+
+// Add adds value to current bucket.
+func (rw *RollingWindow) Add(v float64) {
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+	rw.updateOffset()
+	rw.buckets[rw.offset].add(v)
+}
+
+// Reduce runs fn on all buckets which are still in the window.
+func (rw *RollingWindow) Reduce(fn func(b *Bucket)) {
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+	rw.updateOffset()
+	for i := 0; i < rw.size; i++ {
+		fn(rw.buckets[(rw.offset+1+i)%rw.size])
+	}
+}
+
+// Reset clears all buckets.
+func (rw *RollingWindow) Reset() {
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+	for _, b := range rw.buckets {
+		b.reset()
+	}
+	rw.offset = 0
+	rw.lastTime = rw.now()
+}
+
+func (rw *RollingWindow) span() int {
+	offset := int(rw.now().Sub(rw.lastTime) / rw.interval)
+	if 0 <= offset && offset < rw.size {
+		return offset
+	}
+
+	return rw.size
+}
+
+func (rw *RollingWindow) updateOffset() {
+	span := rw.span()
+	if span <= 0 {
+		return
+	}
+
+	offset := rw.offset
+	// reset expired buckets
+	for i := 0; i < span; i++ {
+		rw.buckets[(offset+i+1)%rw.size].reset()
+	}
+
+	rw.offset = (offset + span) % rw.size
+	// align to interval time boundary
+	rw.lastTime = rw.lastTime.Add(rw.now().Sub(rw.lastTime) / rw.interval * rw.interval)
+}
+
+func (b *Bucket) add(v float64) {
+	b.Sum += v
+	b.Count++
+}
+
+func (b *Bucket) reset() {
+	b.Sum = 0
+	b.Count = 0
+}
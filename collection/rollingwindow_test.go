@@ -0,0 +1,71 @@
+package collection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.now = f.now.Add(d)
+}
+
+func TestNewRollingWindow(t *testing.T) {
+	assert.Panics(t, func() {
+		NewRollingWindow(0, time.Second)
+	})
+}
+
+func TestRollingWindowAdd(t *testing.T) {
+	const size = 3
+	clock := &fakeClock{now: time.Now()}
+	r := NewRollingWindow(size, time.Second, WithClock(clock.Now))
+	listBuckets := func() []float64 {
+		var buckets []float64
+		r.Reduce(func(b *Bucket) {
+			buckets = append(buckets, b.Sum)
+		})
+		return buckets
+	}
+	assert.Equal(t, []float64{0, 0, 0}, listBuckets())
+	r.Add(1)
+	assert.Equal(t, []float64{0, 0, 1}, listBuckets())
+	clock.Advance(time.Second)
+	r.Add(2)
+	r.Add(3)
+	assert.Equal(t, []float64{0, 1, 5}, listBuckets())
+	clock.Advance(time.Second)
+	r.Add(4)
+	r.Add(5)
+	r.Add(6)
+	assert.Equal(t, []float64{1, 5, 15}, listBuckets())
+	clock.Advance(time.Second)
+	r.Add(7)
+	assert.Equal(t, []float64{5, 15, 7}, listBuckets())
+	clock.Advance(10 * time.Second)
+	assert.Equal(t, []float64{0, 0, 0}, listBuckets())
+}
+
+func TestRollingWindowReset(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	r := NewRollingWindow(3, time.Second, WithClock(clock.Now))
+	r.Add(1)
+	clock.Advance(time.Second)
+	r.Add(2)
+	r.Reset()
+
+	var count int64
+	r.Reduce(func(b *Bucket) {
+		count += b.Count
+	})
+	assert.Equal(t, int64(0), count)
+}
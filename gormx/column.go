@@ -0,0 +1,156 @@
+package gormx
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	// ErrUnknownColumn 校验模式下，条件中引用了不允许的列或无法识别的表达式
+	ErrUnknownColumn = errors.New("unknown column")
+	// ErrSubQueryModel 子查询没有绑定模型
+	ErrSubQueryModel = errors.New("subquery must be bound to a model")
+	// ErrNotSoftDelete OnlyDeleted、WithoutDeleted 使用的模型没有 gorm.DeletedAt 类型的字段
+	ErrNotSoftDelete = errors.New("model does not support soft delete")
+)
+
+// columnar 由引用了列的条件实现，返回条件中引用到的全部列或表达式
+type columnar interface {
+	columns() []string
+}
+
+// relational 由 Joins、Preload 实现，返回引用到的关联名
+type relational interface {
+	relations() []string
+}
+
+// Model 将 Query 绑定到模型，开启校验模式：条件中只允许引用模型中的列，否则 WithDB 返回错误而不会生成 SQL
+func (q *Query) Model(model any) *Query {
+	q.model = model
+	return q
+}
+
+// AllowColumns 开启校验模式：条件中只允许引用 columns 中的列，否则 WithDB 返回错误而不会生成 SQL
+//
+// 可以与 Model 同时使用，此时模型中的列与 columns 均被允许。
+func (q *Query) AllowColumns(columns ...string) *Query {
+	if q.allowed == nil {
+		q.allowed = make(map[string]struct{}, len(columns))
+	}
+	for _, c := range columns {
+		q.allowed[c] = struct{}{}
+	}
+	return q
+}
+
+// strict 是否开启了校验模式
+func (q *Query) strict() bool {
+	return q.model != nil || q.allowed != nil
+}
+
+// validate 校验条件中引用的列是否都被允许
+func (q *Query) validate(db *gorm.DB) error {
+	return q.validateWith(func(model any) (*schema.Schema, error) {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		return stmt.Schema, nil
+	})
+}
+
+// validateWith 使用 parse 解析绑定的模型，校验条件中引用的列是否都被允许
+//
+// 绑定了模型时，还允许通过 "关联名"、"关联名.列名" 引用模型的关联及关联表上的列，关联可以嵌套，如 "Orders.Items.id"。
+func (q *Query) validateWith(parse func(model any) (*schema.Schema, error)) error {
+	if !q.strict() {
+		return nil
+	}
+
+	allowed := make(map[string]struct{}, len(q.allowed)+1)
+	allowed["*"] = struct{}{}
+	for c := range q.allowed {
+		allowed[c] = struct{}{}
+	}
+	var sch *schema.Schema
+	if q.model != nil {
+		var err error
+		if sch, err = parse(q.model); err != nil {
+			return err
+		}
+		for _, c := range sch.DBNames {
+			allowed[c] = struct{}{}
+			allowed[sch.Table+"."+c] = struct{}{}
+		}
+	}
+
+	for _, name := range q.relations() {
+		if _, ok := allowed[name]; ok {
+			continue
+		}
+		if _, ok := relationSchema(sch, strings.Split(name, ".")); !ok {
+			return errors.Wrapf(ErrUnknownColumn, "relation %q", name)
+		}
+	}
+	for _, c := range q.columns() {
+		if _, ok := allowed[c]; ok {
+			continue
+		}
+		if !relationColumn(sch, c) {
+			return errors.Wrapf(ErrUnknownColumn, "%q", c)
+		}
+	}
+	return nil
+}
+
+// relationSchema 沿 path 依次查找 sch 的关联，返回最后一个关联的模型
+func relationSchema(sch *schema.Schema, path []string) (*schema.Schema, bool) {
+	if sch == nil || len(path) == 0 {
+		return nil, false
+	}
+	for _, p := range path {
+		rel, ok := sch.Relationships.Relations[p]
+		if !ok {
+			return nil, false
+		}
+		sch = rel.FieldSchema
+	}
+	return sch, true
+}
+
+// relationColumn c 是否为以关联名为前缀的关联表上的列，如 "Company.name"
+func relationColumn(sch *schema.Schema, c string) bool {
+	parts := strings.Split(c, ".")
+	rel, ok := relationSchema(sch, parts[:len(parts)-1])
+	if !ok {
+		return false
+	}
+	column := parts[len(parts)-1]
+	_, ok = rel.FieldsByDBName[column]
+	return ok || column == "*"
+}
+
+// relations 返回 Joins、Preload 引用到的关联名
+func (q *Query) relations() []string {
+	var names []string
+	for _, k := range execOrder {
+		if c, ok := q.conMap[k].(relational); ok {
+			names = append(names, c.relations()...)
+		}
+	}
+	return names
+}
+
+// columns 返回所有条件中引用到的列
+func (q *Query) columns() []string {
+	var columns []string
+	for _, k := range execOrder {
+		if c, ok := q.conMap[k].(columnar); ok {
+			columns = append(columns, c.columns()...)
+		}
+	}
+	return columns
+}
@@ -0,0 +1,102 @@
+package gormx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery_Validate(t *testing.T) {
+	db := newSQLiteDB(t)
+
+	tests := []struct {
+		name    string
+		q       *Query
+		wantErr bool
+	}{
+		{
+			name: "no strict mode",
+			q:    NewQuery().Eq("unknown", 1),
+		},
+		{
+			name: "model columns",
+			q:    NewQuery().Model(&user{}).Eq("name", "bob").Gt("users.age", 1).OrderBy("id desc").Select("id", "name"),
+		},
+		{
+			name:    "unknown column",
+			q:       NewQuery().Model(&user{}).Eq("password", "x"),
+			wantErr: true,
+		},
+		{
+			name:    "unknown column in group",
+			q:       NewQuery().Model(&user{}).OrGroup(func(sub *Query) { sub.Eq("1 = 1 OR name", "x") }),
+			wantErr: true,
+		},
+		{
+			name:    "raw or expression",
+			q:       NewQuery().Model(&user{}).Or("name = ?", "x"),
+			wantErr: true,
+		},
+		{
+			name:    "order injection",
+			q:       NewQuery().Model(&user{}).OrderBy("id; DROP TABLE users"),
+			wantErr: true,
+		},
+		{
+			name: "page order by",
+			q: NewQuery().Model(&user{}).Page(&pageData{
+				Page:     1,
+				PageSize: 10,
+				OrderBy:  "{\"age\":\"descend\"}",
+			}),
+		},
+		{
+			name:    "page order by injection",
+			q:       NewQuery().Model(&user{}).Page(&pageData{OrderBy: "{\"(SELECT 1)\":\"desc\"}"}),
+			wantErr: true,
+		},
+		{
+			name:    "custom order injection",
+			q:       NewQuery().Model(&user{}).CustomOrder("{\"id\":\"asc\",\"sleep(10)\":\"asc\"}", "id desc"),
+			wantErr: true,
+		},
+		{
+			name: "allowed columns",
+			q:    NewQuery().AllowColumns("name", "age").Eq("name", "bob").Like("name", "b").IsNull("age"),
+		},
+		{
+			name:    "not allowed column",
+			q:       NewQuery().AllowColumns("name").Eq("age", 1),
+			wantErr: true,
+		},
+		{
+			name: "model and allowed columns",
+			q:    NewQuery().Model(&user{}).AllowColumns("total").Group("name").OrderBy("total desc"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []user
+			err := tt.q.WithDB(db.Model(&user{})).Find(&got).Error
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrUnknownColumn)
+				assert.Empty(t, got)
+				return
+			}
+			if err != nil {
+				assert.NotErrorIs(t, err, ErrUnknownColumn)
+			}
+		})
+	}
+}
+
+func TestQuery_Model(t *testing.T) {
+	db := newSQLiteDB(t)
+
+	var got []struct {
+		Name string
+	}
+	err := NewQuery().Model(&user{}).Select("name").Gte("age", 25).OrderBy("age").WithDB(db).Find(&got).Error
+	assert.NoError(t, err)
+	assert.Len(t, got, 2)
+}
@@ -0,0 +1,375 @@
+package gormx
+
+import (
+	"gorm.io/gorm"
+)
+
+// composable 由所有条件实现，用于 Clone 及 Merge
+type composable interface {
+	// clone 深拷贝条件
+	clone() Condition
+	// merge 将同类条件 other 的拷贝合并到当前条件中
+	merge(other Condition)
+}
+
+// Clone 深拷贝 Query，拷贝后的 Query 与原 Query 互不影响，参数值本身不会被拷贝
+func (q *Query) Clone() *Query {
+	if q == nil {
+		return nil
+	}
+
+	c := NewQuery()
+	c.model = q.model
+	if q.allowed != nil {
+		c.allowed = make(map[string]struct{}, len(q.allowed))
+		for col := range q.allowed {
+			c.allowed[col] = struct{}{}
+		}
+	}
+	for k, cond := range q.conMap {
+		c.conMap[k] = cond.(composable).clone()
+	}
+	return c
+}
+
+// Merge 将 other 中的条件追加到 q 中，other 不会被修改
+//
+// Limit、Offset、Cursor、CustomOrder 及软删除模式使用 other 中的值，q 未绑定模型时使用 other 绑定的模型，允许的列取并集。
+func (q *Query) Merge(other *Query) *Query {
+	if other == nil {
+		return q
+	}
+
+	if q.model == nil {
+		q.model = other.model
+	}
+	if other.allowed != nil {
+		q.AllowColumns()
+		for col := range other.allowed {
+			q.allowed[col] = struct{}{}
+		}
+	}
+	for _, k := range execOrder {
+		cond, ok := other.conMap[k]
+		if !ok {
+			continue
+		}
+		if exist, ok := q.conMap[k]; ok {
+			exist.(composable).merge(cond)
+		} else {
+			q.conMap[k] = cond.(composable).clone()
+		}
+	}
+	return q
+}
+
+// Scope 转换为 gorm 的 Scope，可以与 db.Scopes 配合使用，之后对 q 的修改不会影响返回的 Scope
+func (q *Query) Scope() func(db *gorm.DB) *gorm.DB {
+	c := q.Clone()
+	return func(db *gorm.DB) *gorm.DB {
+		return c.WithDB(db)
+	}
+}
+
+// Scopes 添加 gorm 的 Scope，在条件之后、Group 及排序之前执行
+func (q *Query) Scopes(fns ...func(db *gorm.DB) *gorm.DB) *Query {
+	cond, ok := q.conMap[kindScopes]
+	if ok {
+		cond.Build("", fns)
+		return q
+	}
+
+	q.conMap[kindScopes] = newScopesCond().Build("", fns)
+	return q
+}
+
+type scopesCond struct {
+	body []func(db *gorm.DB) *gorm.DB
+}
+
+func newScopesCond() *scopesCond {
+	return &scopesCond{
+		body: make([]func(db *gorm.DB) *gorm.DB, 0),
+	}
+}
+
+func (s *scopesCond) Build(_ string, args ...any) Condition {
+	s.body = append(s.body, args[0].([]func(db *gorm.DB) *gorm.DB)...)
+	return s
+}
+
+func (s *scopesCond) Do(db *gorm.DB) *gorm.DB {
+	for _, fn := range s.body {
+		db = fn(db)
+	}
+	return db
+}
+
+func (s *scopesCond) clone() Condition {
+	return &scopesCond{body: append([]func(db *gorm.DB) *gorm.DB{}, s.body...)}
+}
+
+func (s *scopesCond) merge(other Condition) {
+	s.body = append(s.body, other.(*scopesCond).body...)
+}
+
+func cloneItems(items []condItem) []condItem {
+	res := make([]condItem, 0, len(items))
+	for _, item := range items {
+		item.args = append([]any{}, item.args...)
+		item.params = append([]string(nil), item.params...)
+		res = append(res, item)
+	}
+	return res
+}
+
+func cloneQueries(queries []*Query) []*Query {
+	res := make([]*Query, 0, len(queries))
+	for _, sub := range queries {
+		res = append(res, sub.Clone())
+	}
+	return res
+}
+
+func cloneRelations(items []relationItem) []relationItem {
+	res := make([]relationItem, 0, len(items))
+	for _, item := range items {
+		item.args = append([]any{}, item.args...)
+		item.sub = item.sub.Clone()
+		res = append(res, item)
+	}
+	return res
+}
+
+func (e *equalCond) clone() Condition {
+	return &equalCond{body: cloneItems(e.body)}
+}
+
+func (e *equalCond) merge(other Condition) {
+	e.body = append(e.body, cloneItems(other.(*equalCond).body)...)
+}
+
+func (n *notCond) clone() Condition {
+	return &notCond{body: cloneItems(n.body)}
+}
+
+func (n *notCond) merge(other Condition) {
+	n.body = append(n.body, cloneItems(other.(*notCond).body)...)
+}
+
+func (i *inCond) clone() Condition {
+	return &inCond{body: cloneItems(i.body)}
+}
+
+func (i *inCond) merge(other Condition) {
+	i.body = append(i.body, cloneItems(other.(*inCond).body)...)
+}
+
+func (i *notInCond) clone() Condition {
+	return &notInCond{body: cloneItems(i.body)}
+}
+
+func (i *notInCond) merge(other Condition) {
+	i.body = append(i.body, cloneItems(other.(*notInCond).body)...)
+}
+
+func (g *gtCond) clone() Condition {
+	return &gtCond{body: cloneItems(g.body)}
+}
+
+func (g *gtCond) merge(other Condition) {
+	g.body = append(g.body, cloneItems(other.(*gtCond).body)...)
+}
+
+func (g *gteCond) clone() Condition {
+	return &gteCond{body: cloneItems(g.body)}
+}
+
+func (g *gteCond) merge(other Condition) {
+	g.body = append(g.body, cloneItems(other.(*gteCond).body)...)
+}
+
+func (l *ltCond) clone() Condition {
+	return &ltCond{body: cloneItems(l.body)}
+}
+
+func (l *ltCond) merge(other Condition) {
+	l.body = append(l.body, cloneItems(other.(*ltCond).body)...)
+}
+
+func (l *lteCond) clone() Condition {
+	return &lteCond{body: cloneItems(l.body)}
+}
+
+func (l *lteCond) merge(other Condition) {
+	l.body = append(l.body, cloneItems(other.(*lteCond).body)...)
+}
+
+func (b *betweenCond) clone() Condition {
+	return &betweenCond{body: cloneItems(b.body)}
+}
+
+func (b *betweenCond) merge(other Condition) {
+	b.body = append(b.body, cloneItems(other.(*betweenCond).body)...)
+}
+
+func (o *orCond) clone() Condition {
+	return &orCond{body: cloneItems(o.body)}
+}
+
+func (o *orCond) merge(other Condition) {
+	o.body = append(o.body, cloneItems(other.(*orCond).body)...)
+}
+
+func (l *likeCond) clone() Condition {
+	c := &likeCond{body: make([]likeVal, 0, len(l.body))}
+	c.merge(l)
+	return c
+}
+
+func (l *likeCond) merge(other Condition) {
+	for _, lv := range other.(*likeCond).body {
+		lv.params = append([]string(nil), lv.params...)
+		l.body = append(l.body, lv)
+	}
+}
+
+func (i *isNullCond) clone() Condition {
+	return &isNullCond{body: append([]string{}, i.body...)}
+}
+
+func (i *isNullCond) merge(other Condition) {
+	i.body = append(i.body, other.(*isNullCond).body...)
+}
+
+func (n *notNullCond) clone() Condition {
+	return &notNullCond{body: append([]string{}, n.body...)}
+}
+
+func (n *notNullCond) merge(other Condition) {
+	n.body = append(n.body, other.(*notNullCond).body...)
+}
+
+func (n *nestedCond) clone() Condition {
+	return &nestedCond{or: n.or, body: cloneQueries(n.body)}
+}
+
+func (n *nestedCond) merge(other Condition) {
+	n.body = append(n.body, cloneQueries(other.(*nestedCond).body)...)
+}
+
+func (h *havingCond) clone() Condition {
+	return &havingCond{body: cloneQueries(h.body)}
+}
+
+func (h *havingCond) merge(other Condition) {
+	h.body = append(h.body, cloneQueries(other.(*havingCond).body)...)
+}
+
+func (o *orderByCond) clone() Condition {
+	return &orderByCond{body: append([]orderItem{}, o.body...)}
+}
+
+func (o *orderByCond) merge(other Condition) {
+	o.body = append(o.body, other.(*orderByCond).body...)
+}
+
+func (c *customOrderCond) clone() Condition {
+	return &customOrderCond{
+		order:        append([]orderItem(nil), c.order...),
+		defaultOrder: append([]orderItem(nil), c.defaultOrder...),
+		invalid:      append([]orderItem(nil), c.invalid...),
+	}
+}
+
+func (c *customOrderCond) merge(other Condition) {
+	*c = *other.(*customOrderCond).clone().(*customOrderCond)
+}
+
+func (c *deletedCond) clone() Condition {
+	return &deletedCond{mode: c.mode}
+}
+
+func (c *deletedCond) merge(other Condition) {
+	c.mode = other.(*deletedCond).mode
+}
+
+func (l *limitCond) clone() Condition {
+	return &limitCond{body: l.body}
+}
+
+func (l *limitCond) merge(other Condition) {
+	l.body = other.(*limitCond).body
+}
+
+func (o *offsetCond) clone() Condition {
+	return &offsetCond{body: o.body}
+}
+
+func (o *offsetCond) merge(other Condition) {
+	o.body = other.(*offsetCond).body
+}
+
+func (c *cursorCond) clone() Condition {
+	cc := *c
+	cc.orderBy = append([]string(nil), c.orderBy...)
+	cc.fields = append([]cursorColumn(nil), c.fields...)
+	cc.values = append([]any(nil), c.values...)
+	return &cc
+}
+
+func (c *cursorCond) merge(other Condition) {
+	*c = *other.(*cursorCond).clone().(*cursorCond)
+}
+
+func (c *selectCond) clone() Condition {
+	return &selectCond{fields: append([]string{}, c.fields...)}
+}
+
+func (c *selectCond) merge(other Condition) {
+	c.fields = append(c.fields, other.(*selectCond).fields...)
+}
+
+func (c *groupCond) clone() Condition {
+	return &groupCond{fields: append([]string{}, c.fields...)}
+}
+
+func (c *groupCond) merge(other Condition) {
+	c.fields = append(c.fields, other.(*groupCond).fields...)
+}
+
+func (c *distinctCond) clone() Condition {
+	return &distinctCond{fields: append([]string{}, c.fields...)}
+}
+
+func (c *distinctCond) merge(other Condition) {
+	c.fields = append(c.fields, other.(*distinctCond).fields...)
+}
+
+func (j *joinsCond) clone() Condition {
+	return &joinsCond{body: cloneRelations(j.body)}
+}
+
+func (j *joinsCond) merge(other Condition) {
+	j.body = append(j.body, cloneRelations(other.(*joinsCond).body)...)
+}
+
+func (p *preloadCond) clone() Condition {
+	return &preloadCond{body: cloneRelations(p.body)}
+}
+
+func (p *preloadCond) merge(other Condition) {
+	p.body = append(p.body, cloneRelations(other.(*preloadCond).body)...)
+}
+
+func (s *subQueryCond) clone() Condition {
+	c := &subQueryCond{not: s.not, body: make([]subQueryItem, 0, len(s.body))}
+	c.merge(s)
+	return c
+}
+
+func (s *subQueryCond) merge(other Condition) {
+	for _, item := range other.(*subQueryCond).body {
+		s.body = append(s.body, subQueryItem{key: item.key, sub: item.sub.Clone()})
+	}
+}
@@ -0,0 +1,87 @@
+package gormx
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestQuery_Clone(t *testing.T) {
+	db := newDryRunDB(t)
+
+	base := NewQuery().
+		Eq("tenant_id", 1).
+		LikePrefix("name", "b").
+		OrGroup(func(sub *Query) { sub.Eq("a", 1) }).
+		OrderBy("id").
+		Limit(10)
+	want := toSQL(db, base)
+
+	c := base.Clone()
+	assert.Equal(t, want, toSQL(db, c))
+
+	c.Eq("status", "active").LikeSuffix("name", "x").OrderBy("age desc").Limit(20)
+	c.conMap[kindOrGroup].(*nestedCond).body[0].Gt("b", 2)
+	assert.Equal(t, want, toSQL(db, base))
+	assert.Equal(t, "SELECT * FROM `users` WHERE `tenant_id` = 1 AND `status` = \"active\" AND `name` LIKE \"b%\" "+
+		"AND `name` LIKE \"%x\" OR (`a` = 1 AND `b` > 2) ORDER BY `id`,`age` DESC LIMIT 20", toSQL(db, c))
+
+	assert.Nil(t, (*Query)(nil).Clone())
+}
+
+func TestQuery_Merge(t *testing.T) {
+	db := newDryRunDB(t)
+
+	active := NewQuery().Eq("status", "active").IsNull("deleted_at")
+	q := NewQuery().Eq("tenant_id", 1).OrderBy("id").Limit(10).
+		Merge(active).
+		Merge(NewQuery().Gt("age", 18).OrderBy("age desc").Limit(5)).
+		Merge(nil)
+
+	assert.Equal(t, "SELECT * FROM `users` WHERE `tenant_id` = 1 AND `status` = \"active\" AND `age` > 18 "+
+		"AND `deleted_at` IS NULL ORDER BY `id`,`age` DESC LIMIT 5", toSQL(db, q))
+	assert.Equal(t, "SELECT * FROM `users` WHERE `status` = \"active\" AND `deleted_at` IS NULL", toSQL(db, active))
+
+	strict := NewQuery().Model(&user{}).Merge(NewQuery().AllowColumns("status").Eq("status", 1))
+	assert.Equal(t, map[string]struct{}{"status": {}}, strict.allowed)
+	assert.NoError(t, strict.validate(newSQLiteDB(t)))
+}
+
+func TestQuery_Scope(t *testing.T) {
+	db := newSQLiteDB(t)
+
+	adult := NewQuery().Gte("age", 18).OrderBy("age desc")
+	scope := adult.Scope()
+	adult.Eq("name", "nobody")
+
+	var got []user
+	assert.NoError(t, db.Scopes(scope).Limit(2).Find(&got).Error)
+	assert.Equal(t, []int{32, 25}, ages(got))
+
+	got = nil
+	q := NewQuery().Scopes(func(db *gorm.DB) *gorm.DB {
+		return db.Where("name <> ?", "bob")
+	}).Eq("age", 18).OrderBy("id")
+	assert.NoError(t, q.WithDB(db).Find(&got).Error)
+	assert.Equal(t, []int{18}, ages(got))
+}
+
+func TestQuery_ConcurrentRead(t *testing.T) {
+	db := newDryRunDB(t)
+	shared := NewQuery().Eq("tenant_id", 1).OrGroup(func(sub *Query) { sub.In("id", []int{1, 2}) }).OrderBy("id")
+	want := toSQL(db, shared)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			q := shared.Clone().Limit(i + 1)
+			assert.Contains(t, toSQL(db, q), want)
+			assert.Equal(t, want, toSQL(db, shared))
+		}(i)
+	}
+	wg.Wait()
+}
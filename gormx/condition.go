@@ -1,12 +1,13 @@
 package gormx
 
 import (
-	"fmt"
+	"reflect"
 	"strings"
 
-	"github.com/bytedance/sonic"
+	"github.com/pkg/errors"
 	"github.com/xyzbit/gpkg/convertor"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Condition interface {
@@ -14,339 +15,665 @@ type Condition interface {
 	Do(db *gorm.DB) *gorm.DB
 }
 
+// condItem 同类条件中的一项，同一列可以出现多次，按添加的顺序生成 SQL
+type condItem struct {
+	key    string
+	args   []any
+	fc     Function // 作用于列上的函数，为空时直接使用列
+	params []string // 函数除列名外的参数
+}
+
+// newCondItem args 的前 n 个为参数值，其后依次为可选的 Function 及其参数
+func newCondItem(key string, args []any, n int) condItem {
+	item := condItem{key: key, args: args[:n]}
+	if len(args) > n {
+		item.fc = args[n].(Function)
+		for _, p := range args[n+1:] {
+			item.params = append(item.params, p.(string))
+		}
+	}
+	return item
+}
+
+// column 返回条件左侧的列或函数表达式
+func (c condItem) column() any {
+	if c.fc == nil {
+		return clause.Column{Name: c.key}
+	}
+	return c.fc.Expression(append([]string{c.key}, c.params...)...)
+}
+
+// arg 返回第 i 个参数值，使用函数时参数值经过 ConvertVal 转换
+func (c condItem) arg(i int) any {
+	return convertFuncVal(c.fc, c.args[i])
+}
+
+func itemColumns(items []condItem) []string {
+	columns := make([]string, 0, len(items))
+	for _, item := range items {
+		columns = append(columns, item.key)
+	}
+	return columns
+}
+
 type equalCond struct {
-	body map[string]any
+	body []condItem
 }
 
 func newEqualCond() *equalCond {
 	return &equalCond{
-		body: make(map[string]any),
+		body: make([]condItem, 0),
 	}
 }
 
 func (e *equalCond) Build(key string, args ...any) Condition {
-	e.body[key] = args[0]
+	e.body = append(e.body, newCondItem(key, args, 1))
 	return e
 }
 
+func (e *equalCond) columns() []string {
+	return itemColumns(e.body)
+}
+
 func (e *equalCond) Do(db *gorm.DB) *gorm.DB {
-	for k, v := range e.body {
-		db = db.Where(fmt.Sprintf("`%s` = ?", k), v)
+	for _, item := range e.body {
+		db = db.Where("? = ?", item.column(), item.arg(0))
 	}
 	return db
 }
 
 type notCond struct {
-	body map[string]any
+	body []condItem
 }
 
 func newNotCond() *notCond {
 	return &notCond{
-		body: make(map[string]any),
+		body: make([]condItem, 0),
 	}
 }
 
 func (n *notCond) Build(key string, args ...any) Condition {
-	n.body[key] = args[0]
+	n.body = append(n.body, newCondItem(key, args, 1))
 	return n
 }
 
+func (n *notCond) columns() []string {
+	return itemColumns(n.body)
+}
+
 func (n *notCond) Do(db *gorm.DB) *gorm.DB {
-	for k, v := range n.body {
-		db = db.Not(fmt.Sprintf("`%s` = ?", k), v)
+	for _, item := range n.body {
+		db = db.Not("? = ?", item.column(), item.arg(0))
 	}
 	return db
 }
 
 type inCond struct {
-	body map[string]any
+	body []condItem
 }
 
 func newInCond() *inCond {
 	return &inCond{
-		body: make(map[string]any),
+		body: make([]condItem, 0),
 	}
 }
 
 func (i *inCond) Build(key string, args ...any) Condition {
-	i.body[key] = args[0]
+	i.body = append(i.body, newCondItem(key, args, 1))
 	return i
 }
 
+func (i *inCond) columns() []string {
+	return itemColumns(i.body)
+}
+
 func (i *inCond) Do(db *gorm.DB) *gorm.DB {
-	for k, v := range i.body {
-		db = db.Where(fmt.Sprintf("`%s` IN ?", k), v)
+	for _, item := range i.body {
+		db = db.Where("? IN ?", item.column(), item.arg(0))
 	}
 	return db
 }
 
 type notInCond struct {
-	body map[string]any
+	body []condItem
 }
 
 func newNotInCond() *notInCond {
 	return &notInCond{
-		body: make(map[string]any),
+		body: make([]condItem, 0),
 	}
 }
 
 func (i *notInCond) Build(key string, args ...any) Condition {
-	i.body[key] = args[0]
+	i.body = append(i.body, newCondItem(key, args, 1))
 	return i
 }
 
+func (i *notInCond) columns() []string {
+	return itemColumns(i.body)
+}
+
 func (i *notInCond) Do(db *gorm.DB) *gorm.DB {
-	for k, v := range i.body {
-		db = db.Where(fmt.Sprintf("`%s` NOT IN ?", k), v)
+	for _, item := range i.body {
+		db = db.Where("? NOT IN ?", item.column(), item.arg(0))
 	}
 	return db
 }
 
 type gtCond struct {
-	body map[string]any
+	body []condItem
 }
 
 func newGtCond() *gtCond {
 	return &gtCond{
-		body: make(map[string]any),
+		body: make([]condItem, 0),
 	}
 }
 
 func (g *gtCond) Build(key string, args ...any) Condition {
-	g.body[key] = args[0]
+	g.body = append(g.body, newCondItem(key, args, 1))
 	return g
 }
 
+func (g *gtCond) columns() []string {
+	return itemColumns(g.body)
+}
+
 func (g *gtCond) Do(db *gorm.DB) *gorm.DB {
-	for k, v := range g.body {
-		db = db.Where(fmt.Sprintf("`%s` > ?", k), v)
+	for _, item := range g.body {
+		db = db.Where("? > ?", item.column(), item.arg(0))
 	}
 	return db
 }
 
 type gteCond struct {
-	body map[string]any
+	body []condItem
 }
 
 func newGteCond() *gteCond {
 	return &gteCond{
-		body: make(map[string]any),
+		body: make([]condItem, 0),
 	}
 }
 
 func (g *gteCond) Build(key string, args ...any) Condition {
-	g.body[key] = args[0]
+	g.body = append(g.body, newCondItem(key, args, 1))
 	return g
 }
 
+func (g *gteCond) columns() []string {
+	return itemColumns(g.body)
+}
+
 func (g *gteCond) Do(db *gorm.DB) *gorm.DB {
-	for k, v := range g.body {
-		db = db.Where(fmt.Sprintf("`%s` >= ?", k), v)
+	for _, item := range g.body {
+		db = db.Where("? >= ?", item.column(), item.arg(0))
 	}
 	return db
 }
 
 type ltCond struct {
-	body map[string]any
+	body []condItem
 }
 
 func newLtCond() *ltCond {
 	return &ltCond{
-		body: make(map[string]any),
+		body: make([]condItem, 0),
 	}
 }
 
 func (l *ltCond) Build(key string, args ...any) Condition {
-	l.body[key] = args[0]
+	l.body = append(l.body, newCondItem(key, args, 1))
 	return l
 }
 
+func (l *ltCond) columns() []string {
+	return itemColumns(l.body)
+}
+
 func (l *ltCond) Do(db *gorm.DB) *gorm.DB {
-	for k, v := range l.body {
-		db = db.Where(fmt.Sprintf("`%s` < ?", k), v)
+	for _, item := range l.body {
+		db = db.Where("? < ?", item.column(), item.arg(0))
 	}
 	return db
 }
 
 type lteCond struct {
-	body map[string]any
+	body []condItem
 }
 
 func newLteCond() *lteCond {
 	return &lteCond{
-		body: make(map[string]any),
+		body: make([]condItem, 0),
 	}
 }
 
 func (l *lteCond) Build(key string, args ...any) Condition {
-	l.body[key] = args[0]
+	l.body = append(l.body, newCondItem(key, args, 1))
 	return l
 }
 
+func (l *lteCond) columns() []string {
+	return itemColumns(l.body)
+}
+
 func (l *lteCond) Do(db *gorm.DB) *gorm.DB {
-	for k, v := range l.body {
-		db = db.Where(fmt.Sprintf("`%s` <= ?", k), v)
+	for _, item := range l.body {
+		db = db.Where("? <= ?", item.column(), item.arg(0))
 	}
 	return db
 }
 
 type likeCond struct {
-	body map[string]any
+	body []likeVal
 }
 
+// likeMode 参数值在 LIKE 模式中的位置
+type likeMode int
+
+const (
+	likeContains likeMode = iota // %v%
+	likePrefix                   // v%
+	likeSuffix                   // %v
+	likePattern                  // 原样作为模式，不转义
+)
+
 type likeVal struct {
-	val any
-	fc  Function
+	key         string
+	val         any
+	fc          Function
+	params      []string
+	mode        likeMode
+	not         bool
+	insensitive bool
 }
 
 func newLikeCond() *likeCond {
 	return &likeCond{
-		body: make(map[string]any),
+		body: make([]likeVal, 0),
 	}
 }
 
 func (l *likeCond) Build(key string, args ...any) Condition {
-	if len(args) == 2 {
+	if lv, ok := args[0].(likeVal); ok {
+		l.body = append(l.body, lv)
+		return l
+	}
+
+	if len(args) >= 2 {
 		fc, ok := args[1].(Function)
 		if !ok {
 			panic("args[1] must be Function")
 		}
-		l.body[key] = likeVal{
+		lv := likeVal{
+			key: key,
 			val: args[0],
 			fc:  fc,
 		}
+		for _, p := range args[2:] {
+			lv.params = append(lv.params, p.(string))
+		}
+		l.body = append(l.body, lv)
 	} else {
-		l.body[key] = likeVal{
+		l.body = append(l.body, likeVal{
+			key: key,
 			val: args[0],
 			fc:  nil,
-		}
+		})
 	}
 	return l
 }
 
+func (l *likeCond) columns() []string {
+	columns := make([]string, 0, len(l.body))
+	for _, lv := range l.body {
+		columns = append(columns, lv.key)
+	}
+	return columns
+}
+
 func (l *likeCond) Do(db *gorm.DB) *gorm.DB {
-	for k, v := range l.body {
-		lv := v.(likeVal)
-		s := convertor.ToString(lv.val)
-		if lv.fc == nil {
-			db = db.Where(fmt.Sprintf("`%s` LIKE ?", k), "%"+s+"%")
+	for _, lv := range l.body {
+		db = db.Where(lv.expression(db.Dialector.Name()))
+	}
+	return db
+}
+
+func (lv likeVal) expression(dialect string) clause.Expression {
+	var column any = clause.Column{Name: lv.key}
+	s := convertor.ToString(lv.val)
+	if lv.fc != nil {
+		column = lv.fc.Expression(append([]string{lv.key}, lv.params...)...)
+		s = lv.fc.ConvertVal(s)
+	}
+
+	op := "LIKE"
+	if lv.insensitive {
+		if dialect == "postgres" {
+			op = "ILIKE"
 		} else {
-			db = db.Where(fmt.Sprintf("%s LIKE ?", lv.fc.Expression(k)), "%"+lv.fc.ConvertVal(s)+"%")
+			column = clause.Expr{SQL: "LOWER(?)", Vars: []any{column}}
+			s = strings.ToLower(s)
 		}
 	}
-	return db
+	if lv.not {
+		op = "NOT " + op
+	}
+
+	sql := "? " + op + " ?"
+	switch lv.mode {
+	case likePattern:
+		return clause.Expr{SQL: sql, Vars: []any{column, s}}
+	case likePrefix:
+		s = escapeLike(s) + "%"
+	case likeSuffix:
+		s = "%" + escapeLike(s)
+	default:
+		s = "%" + escapeLike(s) + "%"
+	}
+
+	// MySQL、PostgreSQL 默认使用 \ 转义，SQLite、SQL Server 需要显式指定
+	if dialect == "sqlite" || dialect == "sqlserver" {
+		sql += ` ESCAPE '\'`
+	}
+	return clause.Expr{SQL: sql, Vars: []any{column, s}}
+}
+
+var likeReplacer = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike 转义 LIKE 中的通配符，使参数值按字面匹配
+func escapeLike(s string) string {
+	return likeReplacer.Replace(s)
 }
 
 type betweenCond struct {
-	body map[string][]any
+	body []condItem
 }
 
 func newBetweenCond() *betweenCond {
 	return &betweenCond{
-		body: make(map[string][]any),
+		body: make([]condItem, 0),
 	}
 }
 
 func (b *betweenCond) Build(key string, args ...any) Condition {
-	b.body[key] = args
+	b.body = append(b.body, newCondItem(key, args, 2))
 	return b
 }
 
+func (b *betweenCond) columns() []string {
+	return itemColumns(b.body)
+}
+
 func (b *betweenCond) Do(db *gorm.DB) *gorm.DB {
-	for k, v := range b.body {
-		db = db.Where(fmt.Sprintf("`%s` BETWEEN ? AND ?", k), v[0], v[1])
+	for _, item := range b.body {
+		db = db.Where("? BETWEEN ? AND ?", item.column(), item.arg(0), item.arg(1))
 	}
 	return db
 }
 
 type isNullCond struct {
-	body map[string]any
+	body []string
 }
 
 func newIsNullCond() *isNullCond {
 	return &isNullCond{
-		body: make(map[string]any),
+		body: make([]string, 0),
 	}
 }
 
-func (i *isNullCond) Build(key string, args ...any) Condition {
-	i.body[key] = args[0]
+func (i *isNullCond) Build(key string, _ ...any) Condition {
+	i.body = append(i.body, key)
 	return i
 }
 
+func (i *isNullCond) columns() []string {
+	return i.body
+}
+
 func (i *isNullCond) Do(db *gorm.DB) *gorm.DB {
-	for k, v := range i.body {
-		db = db.Where(fmt.Sprintf("`%s` IS NULL", k), v)
+	for _, k := range i.body {
+		db = db.Where("? IS NULL", clause.Column{Name: k})
 	}
 	return db
 }
 
 type notNullCond struct {
-	body map[string]any
+	body []string
 }
 
 func newNotNullCond() *notNullCond {
 	return &notNullCond{
-		body: make(map[string]any),
+		body: make([]string, 0),
 	}
 }
 
-func (i *notNullCond) Build(key string, args ...any) Condition {
-	i.body[key] = args[0]
+func (i *notNullCond) Build(key string, _ ...any) Condition {
+	i.body = append(i.body, key)
 	return i
 }
 
+func (i *notNullCond) columns() []string {
+	return i.body
+}
+
 func (i *notNullCond) Do(db *gorm.DB) *gorm.DB {
-	for k, v := range i.body {
-		db = db.Where(fmt.Sprintf("`%s` IS NOT NULL", k), v)
+	for _, k := range i.body {
+		db = db.Where("? IS NOT NULL", clause.Column{Name: k})
 	}
 	return db
 }
 
 type orCond struct {
-	body map[string]any
+	body []condItem
 }
 
 func newOrCond() *orCond {
 	return &orCond{
-		body: make(map[string]any),
+		body: make([]condItem, 0),
 	}
 }
 
 func (o *orCond) Build(key string, args ...any) Condition {
-	o.body[key] = args[0]
+	o.body = append(o.body, condItem{key: key, args: args[:1]})
 	return o
 }
 
+func (o *orCond) columns() []string {
+	return itemColumns(o.body)
+}
+
 func (o *orCond) Do(db *gorm.DB) *gorm.DB {
-	for k, v := range o.body {
-		db = db.Or(k, v)
+	for _, item := range o.body {
+		db = db.Or(item.key, item.args[0])
 	}
 	return db
 }
 
+type nestedCond struct {
+	or   bool
+	body []*Query
+}
+
+func newNestedCond(or bool) *nestedCond {
+	return &nestedCond{
+		or:   or,
+		body: make([]*Query, 0),
+	}
+}
+
+func (n *nestedCond) Build(_ string, args ...any) Condition {
+	sub := NewQuery()
+	args[0].(func(sub *Query))(sub)
+	n.body = append(n.body, sub)
+	return n
+}
+
+func (n *nestedCond) columns() []string {
+	var columns []string
+	for _, sub := range n.body {
+		columns = append(columns, sub.columns()...)
+	}
+	return columns
+}
+
+func (n *nestedCond) Do(db *gorm.DB) *gorm.DB {
+	for _, sub := range n.body {
+		group := sub.WithDB(newGroupDB(db))
+		if n.or {
+			db = db.Or(group)
+		} else {
+			db = db.Where(group)
+		}
+	}
+	return db
+}
+
+// newGroupDB 基于 db 创建一个不携带任何条件的新会话，用于构建分组条件
+func newGroupDB(db *gorm.DB) *gorm.DB {
+	// Model 会促使 gorm 创建新的 Statement，避免空分组引用到 db 中已有的条件
+	return db.Session(&gorm.Session{NewDB: true}).Model(nil)
+}
+
 type orderByCond struct {
-	body []string
+	body []orderItem
+}
+
+// orderItem 单个排序项，无法解析为 SortField 的表达式原样保存在 raw 中，
+// 客户端传入的不合法排序保存在 invalid 中，不参与排序但在校验模式下会报错
+type orderItem struct {
+	raw     string
+	invalid string
+	field   SortField
 }
 
 func newOrderByCond() *orderByCond {
 	return &orderByCond{
-		body: make([]string, 0),
+		body: make([]orderItem, 0),
 	}
 }
 
 func (o *orderByCond) Build(key string, args ...any) Condition {
-	o.body = append(o.body, key)
+	if len(args) == 1 {
+		o.body = append(o.body, args[0].(orderItem))
+		return o
+	}
+
+	fields, err := ParseSort(key)
+	if err != nil {
+		o.body = append(o.body, orderItem{raw: key})
+		return o
+	}
+	for _, f := range fields {
+		o.body = append(o.body, orderItem{field: f})
+	}
 	return o
 }
 
+func (o *orderByCond) columns() []string {
+	return orderItemColumns(o.body)
+}
+
 func (o *orderByCond) Do(db *gorm.DB) *gorm.DB {
-	for _, v := range o.body {
-		db = db.Order(v)
+	return orderItems(db, o.body)
+}
+
+func orderItemColumns(items []orderItem) []string {
+	columns := make([]string, 0, len(items))
+	for _, item := range items {
+		switch {
+		case item.invalid != "":
+			columns = append(columns, item.invalid)
+		case item.raw != "":
+			columns = append(columns, item.raw)
+		default:
+			columns = append(columns, item.field.Column)
+		}
+	}
+	return columns
+}
+
+func orderItems(db *gorm.DB, items []orderItem) *gorm.DB {
+	for _, item := range items {
+		switch {
+		case item.invalid != "":
+		case item.raw != "":
+			db = db.Order(item.raw)
+		default:
+			db = db.Order(item.field.orderByColumn(db))
+		}
 	}
 	return db
 }
 
+// deletedMode 软删除记录的查询方式
+type deletedMode int
+
+const (
+	deletedExclude deletedMode = iota // 只查询未删除的记录
+	deletedInclude                    // 同时查询已删除的记录
+	deletedOnly                       // 只查询已删除的记录
+)
+
+var deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+
+type deletedCond struct {
+	mode deletedMode
+}
+
+func newDeletedCond() *deletedCond {
+	return &deletedCond{}
+}
+
+func (c *deletedCond) Build(_ string, args ...any) Condition {
+	c.mode = args[0].(deletedMode)
+	return c
+}
+
+func (c *deletedCond) Do(db *gorm.DB) *gorm.DB {
+	switch c.mode {
+	case deletedInclude:
+		return db.Unscoped()
+	case deletedExclude:
+		// 未调用 Unscoped 时由 gorm 添加 deleted_at IS NULL
+		if !db.Statement.Unscoped {
+			return db
+		}
+	}
+
+	if c.mode == deletedOnly {
+		return db.Unscoped().Where(deletedAtExpr{deleted: true})
+	}
+	return db.Where(deletedAtExpr{})
+}
+
+// deletedAtExpr 软删除列的 IS NULL 或 IS NOT NULL 条件，生成 SQL 时才能从 Statement 中拿到模型的 gorm.DeletedAt 列
+type deletedAtExpr struct {
+	deleted bool
+}
+
+func (e deletedAtExpr) Build(builder clause.Builder) {
+	stmt, ok := builder.(*gorm.Statement)
+	if !ok || stmt.Schema == nil {
+		_ = builder.AddError(errors.WithStack(ErrMissingModel))
+		return
+	}
+
+	for _, f := range stmt.Schema.Fields {
+		if f.FieldType != deletedAtType || f.DBName == "" {
+			continue
+		}
+		column := clause.Column{Table: clause.CurrentTable, Name: f.DBName}
+		if e.deleted {
+			clause.Neq{Column: column, Value: nil}.Build(builder)
+		} else {
+			clause.Eq{Column: column, Value: nil}.Build(builder)
+		}
+		return
+	}
+	_ = builder.AddError(errors.Wrap(ErrNotSoftDelete, stmt.Schema.Name))
+}
+
 type limitCond struct {
 	body int
 }
@@ -388,45 +715,45 @@ func (o *offsetCond) Do(db *gorm.DB) *gorm.DB {
 }
 
 type customOrderCond struct {
-	order        string
-	defaultOrder string
+	order        []orderItem
+	defaultOrder []orderItem
+	invalid      []orderItem
 }
 
 func newCustomOrderCond() *customOrderCond {
 	return &customOrderCond{}
 }
 
+// Build order 为客户端传入的排序规则，无法解析或为空时使用 defaultOrder
 func (c *customOrderCond) Build(_ string, args ...any) Condition {
-	c.order = args[0].(string)
-	c.defaultOrder = args[1].(string)
+	c.order, c.defaultOrder, c.invalid = nil, nil, nil
+	order := args[0].(string)
+	fields, err := ParseSort(order)
+	if err != nil {
+		c.invalid = []orderItem{{invalid: order}}
+	}
+	for _, f := range fields {
+		c.order = append(c.order, orderItem{field: f})
+	}
+	if defaultOrder := args[1].(string); defaultOrder != "" {
+		c.defaultOrder = newOrderByCond().Build(defaultOrder).(*orderByCond).body
+	}
 	return c
 }
 
-func (c *customOrderCond) Do(db *gorm.DB) *gorm.DB {
-	if c.order == "" && c.defaultOrder == "" {
-		return db
-	}
-
-	if c.order == "" {
-		db.Order(c.defaultOrder)
-		return db
+func (c *customOrderCond) items() []orderItem {
+	if len(c.order) > 0 {
+		return c.order
 	}
+	return c.defaultOrder
+}
 
-	sort := map[string]string{}
-	err := sonic.UnmarshalString(c.order, &sort)
-	if err != nil {
-		db.Order(c.defaultOrder)
-		return db
-	}
+func (c *customOrderCond) columns() []string {
+	return orderItemColumns(append(c.invalid, c.items()...))
+}
 
-	for k, v := range sort {
-		o := "asc"
-		if !strings.HasPrefix(strings.ToLower(v), "asc") {
-			o = "desc"
-		}
-		db.Order(k + " " + o)
-	}
-	return db
+func (c *customOrderCond) Do(db *gorm.DB) *gorm.DB {
+	return orderItems(db, c.items())
 }
 
 type selectCond struct {
@@ -449,6 +776,10 @@ func (c *selectCond) Build(_ string, args ...any) Condition {
 	return c
 }
 
+func (c *selectCond) columns() []string {
+	return c.fields
+}
+
 func (c *selectCond) Do(db *gorm.DB) *gorm.DB {
 	db = db.Select(c.fields)
 	return db
@@ -474,9 +805,246 @@ func (c *groupCond) Build(_ string, args ...any) Condition {
 	return c
 }
 
+func (c *groupCond) columns() []string {
+	return c.fields
+}
+
 func (c *groupCond) Do(db *gorm.DB) *gorm.DB {
 	for _, v := range c.fields {
 		db = db.Group(v)
 	}
 	return db
 }
+
+type distinctCond struct {
+	fields []string
+}
+
+func newDistinctCond() *distinctCond {
+	return &distinctCond{
+		fields: make([]string, 0),
+	}
+}
+
+func (c *distinctCond) Build(_ string, args ...any) Condition {
+	for _, v := range args {
+		s, ok := v.(string)
+		if ok {
+			c.fields = append(c.fields, s)
+		}
+	}
+	return c
+}
+
+func (c *distinctCond) columns() []string {
+	return c.fields
+}
+
+func (c *distinctCond) Do(db *gorm.DB) *gorm.DB {
+	fields := make([]any, 0, len(c.fields))
+	for _, f := range c.fields {
+		fields = append(fields, f)
+	}
+	return db.Distinct(fields...)
+}
+
+// relationItem 关联查询中的一项，sub 不为空时为关联表上的条件，否则 args 原样传给 gorm
+type relationItem struct {
+	name string
+	args []any
+	sub  *Query
+}
+
+func newRelationItem(name string, args []any) relationItem {
+	item := relationItem{name: name}
+	if len(args) == 1 {
+		if fn, ok := args[0].(func(sub *Query)); ok {
+			item.sub = NewQuery()
+			fn(item.sub)
+			return item
+		}
+	}
+	item.args = args
+	return item
+}
+
+func relationNames(items []relationItem) []string {
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		names = append(names, item.name)
+	}
+	return names
+}
+
+// relationColumns 返回关联上的条件引用的列，prefix 为 true 时条件中的列加上关联名作为前缀
+//
+// 原样传给 gorm 的字符串参数为原始的 SQL 条件，同样被视为列，校验模式下会被拒绝。
+func relationColumns(items []relationItem, prefix bool) []string {
+	columns := make([]string, 0, len(items))
+	for _, item := range items {
+		if item.sub != nil {
+			for _, c := range item.sub.columns() {
+				if prefix {
+					c = item.name + "." + c
+				}
+				columns = append(columns, c)
+			}
+		}
+		for _, arg := range item.args {
+			if s, ok := arg.(string); ok {
+				columns = append(columns, s)
+			}
+		}
+	}
+	return columns
+}
+
+type joinsCond struct {
+	body []relationItem
+}
+
+func newJoinsCond() *joinsCond {
+	return &joinsCond{
+		body: make([]relationItem, 0),
+	}
+}
+
+func (j *joinsCond) Build(key string, args ...any) Condition {
+	j.body = append(j.body, newRelationItem(key, args))
+	return j
+}
+
+func (j *joinsCond) relations() []string {
+	return relationNames(j.body)
+}
+
+// columns 关联表上的条件需要带上关联名，如 "Company.name"
+func (j *joinsCond) columns() []string {
+	return relationColumns(j.body, false)
+}
+
+func (j *joinsCond) Do(db *gorm.DB) *gorm.DB {
+	for _, item := range j.body {
+		if item.sub != nil {
+			db = db.Joins(item.name, item.sub.WithDB(newGroupDB(db)))
+		} else {
+			db = db.Joins(item.name, item.args...)
+		}
+	}
+	return db
+}
+
+type preloadCond struct {
+	body []relationItem
+}
+
+func newPreloadCond() *preloadCond {
+	return &preloadCond{
+		body: make([]relationItem, 0),
+	}
+}
+
+func (p *preloadCond) Build(key string, args ...any) Condition {
+	p.body = append(p.body, newRelationItem(key, args))
+	return p
+}
+
+func (p *preloadCond) relations() []string {
+	return relationNames(p.body)
+}
+
+// columns 预加载的条件作用于关联表，引用的列加上关联名作为前缀
+func (p *preloadCond) columns() []string {
+	return relationColumns(p.body, true)
+}
+
+func (p *preloadCond) Do(db *gorm.DB) *gorm.DB {
+	for _, item := range p.body {
+		if item.sub != nil {
+			sub := item.sub
+			db = db.Preload(item.name, func(tx *gorm.DB) *gorm.DB {
+				return sub.WithDB(tx)
+			})
+		} else {
+			db = db.Preload(item.name, item.args...)
+		}
+	}
+	return db
+}
+
+type subQueryCond struct {
+	not  bool
+	body []subQueryItem
+}
+
+type subQueryItem struct {
+	key string
+	sub *Query
+}
+
+func newSubQueryCond(not bool) *subQueryCond {
+	return &subQueryCond{
+		not:  not,
+		body: make([]subQueryItem, 0),
+	}
+}
+
+func (s *subQueryCond) Build(key string, args ...any) Condition {
+	s.body = append(s.body, subQueryItem{key: key, sub: args[0].(*Query)})
+	return s
+}
+
+func (s *subQueryCond) columns() []string {
+	columns := make([]string, 0, len(s.body))
+	for _, item := range s.body {
+		columns = append(columns, item.key)
+	}
+	return columns
+}
+
+func (s *subQueryCond) Do(db *gorm.DB) *gorm.DB {
+	sql := "? IN (?)"
+	if s.not {
+		sql = "? NOT IN (?)"
+	}
+	for _, item := range s.body {
+		if item.sub.model == nil {
+			_ = db.AddError(errors.Wrapf(ErrSubQueryModel, "%s", item.key))
+			return db
+		}
+		db = db.Where(sql, clause.Column{Name: item.key}, item.sub.WithDB(newGroupDB(db)))
+	}
+	return db
+}
+
+type havingCond struct {
+	body []*Query
+}
+
+func newHavingCond() *havingCond {
+	return &havingCond{
+		body: make([]*Query, 0),
+	}
+}
+
+func (h *havingCond) Build(_ string, args ...any) Condition {
+	sub := NewQuery()
+	args[0].(func(sub *Query))(sub)
+	h.body = append(h.body, sub)
+	return h
+}
+
+func (h *havingCond) columns() []string {
+	var columns []string
+	for _, sub := range h.body {
+		columns = append(columns, sub.columns()...)
+	}
+	return columns
+}
+
+func (h *havingCond) Do(db *gorm.DB) *gorm.DB {
+	for _, sub := range h.body {
+		db = db.Having(sub.WithDB(newGroupDB(db)))
+	}
+	return db
+}
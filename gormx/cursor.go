@@ -0,0 +1,230 @@
+package gormx
+
+import (
+	"context"
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/bytedance/sonic"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const defaultCursorColumn = "id"
+
+// ErrInvalidCursor 游标无法解析，或与排序字段不匹配
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+var cursorSchemaCache = &sync.Map{}
+
+type cursorColumn struct {
+	name string
+	desc bool
+}
+
+type cursorToken struct {
+	Columns []string     `json:"c"`
+	Values  []typedValue `json:"v"`
+}
+
+type cursorCond struct {
+	cursor  string
+	orderBy []string
+	fields  []cursorColumn
+	values  []any
+	size    int
+	err     error
+}
+
+func newCursorCond() *cursorCond {
+	return &cursorCond{}
+}
+
+func (c *cursorCond) Build(key string, args ...any) Condition {
+	c.cursor, c.orderBy = key, args[1].([]string)
+	c.fields = parseCursorColumns(c.orderBy)
+	c.size = args[0].(int)
+	c.values, c.err = decodeCursor(key, c.fields)
+	return c
+}
+
+func (c *cursorCond) columns() []string {
+	columns := make([]string, 0, len(c.fields))
+	for _, col := range c.fields {
+		columns = append(columns, col.name)
+	}
+	return columns
+}
+
+func (c *cursorCond) Do(db *gorm.DB) *gorm.DB {
+	if c.err != nil {
+		_ = db.AddError(c.err)
+		return db
+	}
+
+	if len(c.values) > 0 {
+		db = db.Where(seekExpr(c.fields, c.values))
+	}
+	for _, col := range c.fields {
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: col.name}, Desc: col.desc})
+	}
+	if c.size > 0 {
+		db = db.Limit(c.size)
+	}
+	return db
+}
+
+// Cursor 游标分页（keyset pagination），根据上一页最后一行的排序字段值定位下一页
+//
+// orderBy 的格式为 "col [asc|desc]"，支持升降序混合，缺省时为 "id"；最后一个字段不是 "id" 时自动追加 "id" 作为决胜字段，
+// 方向与最后一个字段一致，因此模型需要唯一的 id 列，排序字段的值不能为 NULL。cursor 为空表示第一页，后续页使用 EncodeCursor 根据上一页最后一行生成的游标。
+// cursor 无法解析时 WithDB 返回 ErrInvalidCursor。
+func (q *Query) Cursor(cursor string, size int, orderBy ...string) *Query {
+	cond, ok := q.conMap[kindCursor]
+	if ok {
+		cond.Build(cursor, size, orderBy)
+		return q
+	}
+
+	q.conMap[kindCursor] = newCursorCond().Build(cursor, size, orderBy)
+	return q
+}
+
+// EncodeCursor 根据 row 中排序字段的值生成下一页的游标，orderBy 需与 Query.Cursor 一致
+//
+// row 可以是模型结构体（按 gorm 默认命名规则匹配列名）或 map[string]any。
+func EncodeCursor(row any, orderBy ...string) (string, error) {
+	columns := parseCursorColumns(orderBy)
+	token := cursorToken{
+		Columns: make([]string, 0, len(columns)),
+		Values:  make([]typedValue, 0, len(columns)),
+	}
+	for _, col := range columns {
+		v, err := rowValue(row, col.name)
+		if err != nil {
+			return "", err
+		}
+		cv, err := encodeTypedValue(v)
+		if err != nil {
+			return "", errors.Wrapf(err, "column %s", col.name)
+		}
+		token.Columns = append(token.Columns, col.name)
+		token.Values = append(token.Values, cv)
+	}
+
+	b, err := sonic.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func parseCursorColumns(orderBy []string) []cursorColumn {
+	columns := make([]cursorColumn, 0, len(orderBy))
+	for _, o := range orderBy {
+		parts := strings.Fields(o)
+		if len(parts) == 0 {
+			continue
+		}
+		col := cursorColumn{name: parts[0]}
+		if len(parts) > 1 {
+			col.desc = strings.EqualFold(parts[1], "desc")
+		}
+		columns = append(columns, col)
+	}
+	// 最后一个字段不是 id 时追加 id 作为决胜字段，方向与最后一个字段一致，避免排序值相同的行在翻页时被跳过或重复
+	if len(columns) == 0 {
+		columns = append(columns, cursorColumn{name: defaultCursorColumn})
+	} else if last := columns[len(columns)-1]; !strings.EqualFold(last.name, defaultCursorColumn) {
+		columns = append(columns, cursorColumn{name: defaultCursorColumn, desc: last.desc})
+	}
+	return columns
+}
+
+// seekExpr 生成 a > ? OR (a = ? AND b < ?) ... 形式的定位条件，兼容升降序混合及不支持行值比较的数据库
+func seekExpr(columns []cursorColumn, values []any) clause.Expression {
+	var (
+		sql  strings.Builder
+		vars []any
+	)
+	for i, col := range columns {
+		if i > 0 {
+			sql.WriteString(" OR (")
+		}
+		for j := 0; j < i; j++ {
+			sql.WriteString("? = ? AND ")
+			vars = append(vars, clause.Column{Name: columns[j].name}, values[j])
+		}
+		if col.desc {
+			sql.WriteString("? < ?")
+		} else {
+			sql.WriteString("? > ?")
+		}
+		vars = append(vars, clause.Column{Name: col.name}, values[i])
+		if i > 0 {
+			sql.WriteByte(')')
+		}
+	}
+	// gorm 会为包含 AND/OR 的表达式加上括号
+	return clause.Expr{SQL: sql.String(), Vars: vars}
+}
+
+func decodeCursor(cursor string, columns []cursorColumn) ([]any, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidCursor, err.Error())
+	}
+	var token cursorToken
+	if err := sonic.Unmarshal(b, &token); err != nil {
+		return nil, errors.Wrap(ErrInvalidCursor, err.Error())
+	}
+	if len(token.Columns) != len(columns) || len(token.Values) != len(columns) {
+		return nil, errors.Wrap(ErrInvalidCursor, "columns mismatch")
+	}
+
+	values := make([]any, 0, len(columns))
+	for i, col := range columns {
+		if token.Columns[i] != col.name {
+			return nil, errors.Wrap(ErrInvalidCursor, "columns mismatch")
+		}
+		v, err := decodeTypedValue(token.Values[i])
+		if err != nil {
+			return nil, errors.Wrap(ErrInvalidCursor, err.Error())
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func rowValue(row any, column string) (any, error) {
+	rv := reflect.Indirect(reflect.ValueOf(row))
+	if rv.Kind() == reflect.Map {
+		if m, ok := row.(map[string]any); ok {
+			v, ok := m[column]
+			if !ok {
+				return nil, errors.Errorf("column %s not found in row", column)
+			}
+			return v, nil
+		}
+		return nil, errors.New("row must be struct or map[string]any")
+	}
+
+	s, err := schema.Parse(row, cursorSchemaCache, schema.NamingStrategy{})
+	if err != nil {
+		return nil, err
+	}
+	field := s.LookUpField(column)
+	if field == nil {
+		return nil, errors.Errorf("column %s not found in row", column)
+	}
+	v, _ := field.ValueOf(context.Background(), rv)
+	return v, nil
+}
@@ -0,0 +1,84 @@
+package gormx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery_CursorSQL(t *testing.T) {
+	db := newPostgresDryRunDB(t)
+
+	cursor, err := EncodeCursor(&user{ID: 7, Age: 20}, "age desc", "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := NewQuery().Eq("name", "bob").Cursor(cursor, 10, "age desc", "id")
+	want := `SELECT * FROM "users" WHERE "name" = 'bob' AND ("age" < 20 OR ("age" = 20 AND "id" > 7)) ` +
+		`ORDER BY "age" DESC,"id" LIMIT 10`
+	assert.Equal(t, want, toSQL(db, q))
+
+	q = NewQuery().Cursor("", 10)
+	assert.Equal(t, `SELECT * FROM "users" ORDER BY "id" LIMIT 10`, toSQL(db, q))
+
+	q = NewQuery().Cursor("", 10, "age desc")
+	assert.Equal(t, `SELECT * FROM "users" ORDER BY "age" DESC,"id" DESC LIMIT 10`, toSQL(db, q))
+}
+
+func TestQuery_Cursor(t *testing.T) {
+	db := newSQLiteDB(t)
+	more := []user{
+		{Name: "Dave", Age: 25},
+		{Name: "Eve", Age: 18},
+		{Name: "Frank", Age: 40},
+	}
+	if err := db.Create(&more).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	pages := func(size int, orderBy ...string) []string {
+		var (
+			cursor string
+			names  []string
+		)
+		for page := 0; page < 10; page++ {
+			var rows []user
+			err := NewQuery().Cursor(cursor, size, orderBy...).WithDB(db).Find(&rows).Error
+			if !assert.NoError(t, err) || len(rows) == 0 {
+				break
+			}
+			for _, r := range rows {
+				names = append(names, r.Name)
+			}
+
+			cursor, err = EncodeCursor(rows[len(rows)-1], orderBy...)
+			if !assert.NoError(t, err) {
+				break
+			}
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"Frank", "Carol", "Dave", "bob", "Alice", "Eve"}, pages(2, "age desc", "name asc", "id"))
+	// age 不唯一，自动追加 id desc 作为决胜字段，翻页时不会跳过 age 相同的行
+	assert.Equal(t, []string{"Frank", "Carol", "Dave", "bob", "Eve", "Alice"}, pages(1, "age desc"))
+	assert.Equal(t, []string{"Alice", "Eve", "bob", "Dave", "Carol", "Frank"}, pages(3, "age"))
+}
+
+func TestQuery_CursorInvalid(t *testing.T) {
+	db := newSQLiteDB(t)
+
+	var rows []user
+	err := NewQuery().Cursor("not a cursor", 10).WithDB(db).Find(&rows).Error
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	cursor, err := EncodeCursor(map[string]any{"age": 18, "id": 1}, "age", "id")
+	if assert.NoError(t, err) {
+		err = NewQuery().Cursor(cursor, 10, "name", "id").WithDB(db).Find(&rows).Error
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+	}
+
+	_, err = EncodeCursor(&user{}, "unknown")
+	assert.Error(t, err)
+}
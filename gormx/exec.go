@@ -0,0 +1,119 @@
+package gormx
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/xyzbit/gpkg/ctxwrap"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound First 未查询到记录
+var ErrNotFound = errors.New("record not found")
+
+// PageResult 分页查询的结果
+type PageResult[T any] struct {
+	List      []T    `json:"list"`
+	Total     int64  `json:"total"`
+	Page      uint64 `json:"page"`
+	PageSize  uint64 `json:"page_size"`
+	TotalPage uint64 `json:"total_page"`
+}
+
+// Find 查询满足 q 的全部记录
+func Find[T any](ctx context.Context, db *gorm.DB, q *Query) ([]T, error) {
+	var list []T
+	if err := q.WithDB(contextDB(ctx, db)).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// First 查询满足 q 的第一条记录，未设置排序时按主键排序，不存在时返回 ErrNotFound
+func First[T any](ctx context.Context, db *gorm.DB, q *Query) (T, error) {
+	var item T
+	err := q.WithDB(contextDB(ctx, db)).First(&item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return item, errors.WithStack(ErrNotFound)
+	}
+	return item, err
+}
+
+// Count 统计满足 q 的记录数，忽略 q 中的 Select、Preload、排序、游标及 Limit/Offset
+func Count[T any](ctx context.Context, db *gorm.DB, q *Query) (int64, error) {
+	var total int64
+	err := q.countQuery().WithDB(modelDB[T](contextDB(ctx, db), q)).Count(&total).Error
+	return total, err
+}
+
+// Exists 判断是否存在满足 q 的记录
+func Exists[T any](ctx context.Context, db *gorm.DB, q *Query) (bool, error) {
+	var one int
+	res := q.countQuery().WithDB(modelDB[T](contextDB(ctx, db), q)).Select("1").Limit(1).Scan(&one)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// Paginate 按 page 分页查询，同时返回满足条件的总数，q 不会被修改
+func Paginate[T any](ctx context.Context, db *gorm.DB, q *Query, page Page) (PageResult[T], error) {
+	res := PageResult[T]{}
+	if page != nil {
+		res.Page, res.PageSize = page.GetPage(), page.GetPageSize()
+	}
+
+	list, err := Find[T](ctx, db, q.Clone().Page(page))
+	if err != nil {
+		return res, err
+	}
+	total, err := Count[T](ctx, db, q)
+	if err != nil {
+		return res, err
+	}
+
+	res.List, res.Total = list, total
+	switch {
+	case res.PageSize > 0:
+		res.TotalPage = (uint64(total) + res.PageSize - 1) / res.PageSize
+	case total > 0:
+		res.TotalPage = 1
+	}
+	return res, nil
+}
+
+// contextDB 优先使用 ctx 中的事务，事务已结束时输出警告
+func contextDB(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx := ctxwrap.FromGormDBContext(ctx); tx != nil {
+		if state := txStateFromContext(ctx); state != nil && state.done.Load() {
+			tx.Logger.Warn(ctx, "gormx: transaction context is used after the transaction has finished")
+		}
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// modelDB q 未绑定 model 时使用 T 作为 model
+func modelDB[T any](db *gorm.DB, q *Query) *gorm.DB {
+	if q.model != nil {
+		return db
+	}
+	return db.Model(new(T))
+}
+
+// countQuery 返回去掉了 Select、Preload、排序、游标及分页条件的查询，条件本身与 q 共享
+func (q *Query) countQuery() *Query {
+	cq := &Query{
+		conMap:  make(map[Kind]Condition, len(q.conMap)),
+		model:   q.model,
+		allowed: q.allowed,
+	}
+	for k, cond := range q.conMap {
+		switch k {
+		case kindSelect, kindPreload, kindOrderBy, kindCustomOrder, kindCursor, kindLimit, kindOffset:
+			continue
+		}
+		cq.conMap[k] = cond
+	}
+	return cq
+}
@@ -0,0 +1,98 @@
+package gormx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/xyzbit/gpkg/ctxwrap"
+	"gorm.io/gorm"
+)
+
+func TestFind(t *testing.T) {
+	db := newSQLiteDB(t)
+	ctx := context.Background()
+
+	got, err := Find[user](ctx, db, NewQuery().Gte("age", 25).OrderBy("age desc"))
+	assert.NoError(t, err)
+	assert.Equal(t, []int{32, 25}, ages(got))
+
+	type nameOnly struct{ Name string }
+	names, err := Find[nameOnly](ctx, db, NewQuery().Model(&user{}).Select("name").OrderBy("id"))
+	assert.NoError(t, err)
+	assert.Equal(t, []nameOnly{{"Alice"}, {"bob"}, {"Carol"}}, names)
+}
+
+func TestFirst(t *testing.T) {
+	db := newSQLiteDB(t)
+	ctx := context.Background()
+
+	got, err := First[user](ctx, db, NewQuery().Gt("age", 18))
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", got.Name)
+
+	_, err = First[user](ctx, db, NewQuery().Gt("age", 100))
+	assert.True(t, errors.Is(err, ErrNotFound), err)
+}
+
+func TestCountAndExists(t *testing.T) {
+	db := newSQLiteDB(t)
+	ctx := context.Background()
+
+	q := NewQuery().Gte("age", 18).Select("name").OrderBy("age desc").Limit(1).Offset(1)
+	total, err := Count[user](ctx, db, q)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+
+	ok, err := Exists[user](ctx, db, NewQuery().Eq("name", "bob"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = Exists[user](ctx, db, NewQuery().Eq("name", "dave"))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = Count[user](ctx, db, NewQuery().Model(&user{}).Eq("unknown", 1))
+	assert.True(t, errors.Is(err, ErrUnknownColumn), err)
+}
+
+func TestPaginate(t *testing.T) {
+	db := newSQLiteDB(t)
+	ctx := context.Background()
+
+	res, err := Paginate[user](ctx, db, NewQuery().Gt("age", 0), &pageData{Page: 2, PageSize: 2, OrderBy: "age:desc"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), res.Total)
+	assert.Equal(t, uint64(2), res.TotalPage)
+	assert.Equal(t, uint64(2), res.Page)
+	assert.Equal(t, []int{18}, ages(res.List))
+
+	res, err = Paginate[user](ctx, db, NewQuery().Gt("age", 100), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), res.Total)
+	assert.Equal(t, uint64(0), res.TotalPage)
+	assert.Empty(t, res.List)
+}
+
+func TestFind_ContextTransaction(t *testing.T) {
+	db := newSQLiteDB(t)
+	errRollback := errors.New("rollback")
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user{Name: "Dave", Age: 40}).Error; err != nil {
+			return err
+		}
+
+		txCtx := ctxwrap.NewGormDBContext(context.Background(), tx)
+		ok, err := Exists[user](txCtx, db, NewQuery().Eq("name", "Dave"))
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		return errRollback
+	})
+	assert.Equal(t, errRollback, err)
+
+	ok, err := Exists[user](context.Background(), db, NewQuery().Eq("name", "Dave"))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,342 @@
+package gormx
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	filterSortKey     = "sort"
+	filterPageKey     = "page"
+	filterPageSizeKey = "page_size"
+)
+
+const (
+	// DefaultPageSize ParseFilter 未指定 page_size 时使用的分页大小，可以通过 WithDefaultPageSize 修改
+	DefaultPageSize = 20
+	// DefaultMaxPageSize ParseFilter 允许的最大 page_size，可以通过 WithMaxPageSize 修改
+	DefaultMaxPageSize = 1000
+)
+
+// FilterOption ParseFilter 的选项
+type FilterOption func(o *filterOptions)
+
+type filterOptions struct {
+	pageSize    uint64
+	maxPageSize uint64
+}
+
+// WithDefaultPageSize 未指定 page_size 时使用的分页大小，超过最大值时使用最大值
+func WithDefaultPageSize(size int) FilterOption {
+	return func(o *filterOptions) {
+		if size > 0 {
+			o.pageSize = uint64(size)
+		}
+	}
+}
+
+// WithMaxPageSize 允许的最大 page_size
+func WithMaxPageSize(size int) FilterOption {
+	return func(o *filterOptions) {
+		if size > 0 {
+			o.maxPageSize = uint64(size)
+		}
+	}
+}
+
+// FieldType 过滤字段的类型，用于将参数值转换为对应的 Go 类型
+type FieldType int
+
+const (
+	TypeString FieldType = iota
+	TypeInt
+	TypeFloat
+	TypeBool
+	TypeTime // 支持 RFC3339 及 2006-01-02 格式
+)
+
+// Field 描述一个允许过滤的字段
+type Field struct {
+	Column   string    // 列名，默认与参数名相同
+	Type     FieldType // 参数值的类型
+	Ops      []string  // 允许的操作符，为空时允许全部操作符
+	Sortable bool      // 是否允许排序
+}
+
+// Schema 允许过滤的字段，key 为参数名
+type Schema map[string]Field
+
+// FieldError 单个参数的校验错误
+type FieldError struct {
+	Field  string
+	Op     string
+	Value  string
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	if e.Op == "" {
+		return fmt.Sprintf("%s: %s", e.Field, e.Reason)
+	}
+	return fmt.Sprintf("%s[%s]: %s", e.Field, e.Op, e.Reason)
+}
+
+// FilterError 解析过滤参数时的全部校验错误
+type FilterError struct {
+	Errors []*FieldError
+}
+
+func (e *FilterError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		msgs = append(msgs, fe.Error())
+	}
+	return "invalid filter: " + strings.Join(msgs, "; ")
+}
+
+// Is 使 errors.Is(err, ErrInvalidFilter) 成立
+func (e *FilterError) Is(target error) bool {
+	return target == ErrInvalidFilter
+}
+
+// ParseFilter 将 URL 查询参数解析为 Query，只允许 schema 中声明的字段
+//
+// 支持的参数格式：
+//
+//	age[gte]=18           操作符：eq、ne、gt、gte、lt、lte、like、in、nin、between、null
+//	status[in]=a,b        in、nin、between 的多个值以逗号分隔
+//	name=bob              省略操作符时为 eq
+//	sort=-created_at,id   以 - 开头表示降序，字段需声明 Sortable
+//	page=2&page_size=20   分页，page_size 不能超过 WithMaxPageSize 设置的最大值，默认为 DefaultMaxPageSize
+//
+// 未指定 page_size 时按 WithDefaultPageSize 设置的大小分页，默认为 DefaultPageSize，保证结果数量总是有上限。
+// 参数不合法时返回 *FilterError，包含全部字段的错误。
+func ParseFilter(values url.Values, allowed Schema, opts ...FilterOption) (*Query, error) {
+	o := &filterOptions{pageSize: DefaultPageSize, maxPageSize: DefaultMaxPageSize}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	q := NewQuery()
+	ferr := &FilterError{}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		switch key {
+		case filterSortKey, filterPageKey, filterPageSizeKey:
+			continue
+		}
+
+		name, op := splitFilterKey(key)
+		field, ok := allowed[name]
+		if !ok {
+			ferr.add(name, op, "", "unknown field")
+			continue
+		}
+		if !field.allowOp(op) {
+			ferr.add(name, op, "", "operator not allowed")
+			continue
+		}
+
+		column := field.Column
+		if column == "" {
+			column = name
+		}
+		for _, raw := range values[key] {
+			if reason := applyFilter(q, column, op, field.Type, raw); reason != "" {
+				ferr.add(name, op, raw, reason)
+			}
+		}
+	}
+
+	parseSortParam(q, values.Get(filterSortKey), allowed, ferr)
+	parsePageParam(q, values.Get(filterPageKey), values.Get(filterPageSizeKey), o, ferr)
+
+	if len(ferr.Errors) > 0 {
+		return nil, ferr
+	}
+	return q, nil
+}
+
+func (e *FilterError) add(field, op, value, reason string) {
+	e.Errors = append(e.Errors, &FieldError{Field: field, Op: op, Value: value, Reason: reason})
+}
+
+func (f Field) allowOp(op string) bool {
+	if len(f.Ops) == 0 {
+		return true
+	}
+	for _, o := range f.Ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// splitFilterKey 将 age[gte] 拆分为 age 和 gte，无操作符时为 eq
+func splitFilterKey(key string) (string, string) {
+	if i := strings.IndexByte(key, '['); i > 0 && strings.HasSuffix(key, "]") {
+		return key[:i], strings.ToLower(key[i+1 : len(key)-1])
+	}
+	return key, "eq"
+}
+
+// applyFilter 将单个参数应用到 q 上，失败时返回原因
+func applyFilter(q *Query, column, op string, typ FieldType, raw string) string {
+	switch op {
+	case "eq", "ne", "gt", "gte", "lt", "lte", "like":
+		v, err := coerce(typ, raw)
+		if err != nil {
+			return err.Error()
+		}
+		switch op {
+		case "eq":
+			q.Eq(column, v)
+		case "ne":
+			q.Not(column, v)
+		case "gt":
+			q.Gt(column, v)
+		case "gte":
+			q.Gte(column, v)
+		case "lt":
+			q.Lt(column, v)
+		case "lte":
+			q.Lte(column, v)
+		case "like":
+			q.Like(column, v)
+		}
+	case "in", "nin", "between":
+		parts := strings.Split(raw, ",")
+		if op == "between" && len(parts) != 2 {
+			return "between requires 2 values"
+		}
+		vs := make([]any, 0, len(parts))
+		for _, p := range parts {
+			v, err := coerce(typ, p)
+			if err != nil {
+				return err.Error()
+			}
+			vs = append(vs, v)
+		}
+		switch op {
+		case "in":
+			q.In(column, vs)
+		case "nin":
+			q.NotIn(column, vs)
+		case "between":
+			q.Between(column, vs[0], vs[1])
+		}
+	case "null":
+		isNull, err := strconv.ParseBool(raw)
+		if err != nil {
+			return "invalid bool"
+		}
+		if isNull {
+			q.IsNull(column)
+		} else {
+			q.NotNull(column)
+		}
+	default:
+		return "unsupported operator"
+	}
+	return ""
+}
+
+// coerce 将参数值转换为 typ 对应的类型
+func coerce(typ FieldType, raw string) (any, error) {
+	raw = strings.TrimSpace(raw)
+	switch typ {
+	case TypeInt:
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid int %q", raw)
+		}
+		return v, nil
+	case TypeFloat:
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %q", raw)
+		}
+		return v, nil
+	case TypeBool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bool %q", raw)
+		}
+		return v, nil
+	case TypeTime:
+		for _, layout := range []string{time.RFC3339, time.DateOnly} {
+			if v, err := time.Parse(layout, raw); err == nil {
+				return v, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid time %q", raw)
+	default:
+		return raw, nil
+	}
+}
+
+func parseSortParam(q *Query, raw string, allowed Schema, ferr *FilterError) {
+	if raw == "" {
+		return
+	}
+
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		desc := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+
+		field, ok := allowed[name]
+		if !ok || !field.Sortable {
+			ferr.add(filterSortKey, "", name, "field not sortable")
+			continue
+		}
+		column := field.Column
+		if column == "" {
+			column = name
+		}
+		q.Sort(SortField{Column: column, Desc: desc})
+	}
+}
+
+func parsePageParam(q *Query, rawPage, rawSize string, o *filterOptions, ferr *FilterError) {
+	page, size := uint64(1), min(o.pageSize, o.maxPageSize)
+	var err error
+	if rawPage != "" {
+		if page, err = strconv.ParseUint(rawPage, 10, 64); err != nil || page == 0 {
+			ferr.add(filterPageKey, "", rawPage, "page must be a positive integer")
+			return
+		}
+	}
+	if rawSize != "" {
+		if size, err = strconv.ParseUint(rawSize, 10, 64); err != nil || size == 0 {
+			ferr.add(filterPageSizeKey, "", rawSize, "page_size must be a positive integer")
+			return
+		}
+		if size > o.maxPageSize {
+			ferr.add(filterPageSizeKey, "", rawSize, fmt.Sprintf("page_size must not exceed %d", o.maxPageSize))
+			return
+		}
+	}
+	// offset 为 (page-1)*size，不能超出 int 的范围
+	if page-1 > uint64(math.MaxInt)/size {
+		ferr.add(filterPageKey, "", rawPage, "page is too large")
+		return
+	}
+
+	q.Limit(int(size))
+	if page > 1 {
+		q.Offset(int((page - 1) * size))
+	}
+}
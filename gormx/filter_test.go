@@ -0,0 +1,131 @@
+package gormx
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var testSchema = Schema{
+	"age":        {Type: TypeInt, Sortable: true},
+	"name":       {Type: TypeString, Ops: []string{"eq", "like"}},
+	"status":     {Type: TypeString},
+	"score":      {Type: TypeFloat},
+	"vip":        {Column: "is_vip", Type: TypeBool},
+	"created_at": {Type: TypeTime, Sortable: true},
+}
+
+func TestParseFilter(t *testing.T) {
+	db := newDryRunDB(t)
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "operators",
+			query: "age[gte]=18&age[lt]=60&status[in]=a,b&name[like]=bo&vip=true&score[between]=1.5,3",
+			want: "SELECT * FROM `users` WHERE `is_vip` = true AND `status` IN (\"a\",\"b\") AND `age` >= 18 AND `age` < 60 " +
+				"AND `name` LIKE \"%bo%\" AND (`score` BETWEEN 1.5 AND 3) LIMIT 20",
+		},
+		{
+			name:  "null",
+			query: "status[null]=true&name[eq]=bob&created_at[null]=false",
+			want:  "SELECT * FROM `users` WHERE `name` = \"bob\" AND `status` IS NULL AND `created_at` IS NOT NULL LIMIT 20",
+		},
+		{
+			name:  "time",
+			query: "created_at[gte]=2024-01-02",
+			want:  "SELECT * FROM `users` WHERE `created_at` >= \"2024-01-02 00:00:00\" LIMIT 20",
+		},
+		{
+			name:  "sort and page",
+			query: "sort=-created_at,age&page=2&page_size=20",
+			want:  "SELECT * FROM `users` ORDER BY `created_at` DESC,`age` LIMIT 20 OFFSET 20",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			q, err := ParseFilter(values, testSchema)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, toSQL(db, q))
+			}
+		})
+	}
+}
+
+func TestParseFilter_Error(t *testing.T) {
+	values, err := url.ParseQuery("age[gte]=abc&password=x&name[gt]=a&score[between]=1&sort=name&page=0&status[regexp]=a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ParseFilter(values, testSchema)
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+
+	var ferr *FilterError
+	if assert.True(t, errors.As(err, &ferr)) {
+		assert.Equal(t, []*FieldError{
+			{Field: "age", Op: "gte", Value: "abc", Reason: "invalid int \"abc\""},
+			{Field: "name", Op: "gt", Reason: "operator not allowed"},
+			{Field: "password", Op: "eq", Reason: "unknown field"},
+			{Field: "score", Op: "between", Value: "1", Reason: "between requires 2 values"},
+			{Field: "status", Op: "regexp", Value: "a", Reason: "unsupported operator"},
+			{Field: "sort", Value: "name", Reason: "field not sortable"},
+			{Field: "page", Value: "0", Reason: "page must be a positive integer"},
+		}, ferr.Errors)
+	}
+}
+
+func TestParseFilter_PageLimit(t *testing.T) {
+	db := newDryRunDB(t)
+	parse := func(query string, opts ...FilterOption) (*Query, []*FieldError) {
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q, err := ParseFilter(values, testSchema, opts...)
+		var ferr *FilterError
+		if errors.As(err, &ferr) {
+			return q, ferr.Errors
+		}
+		return q, nil
+	}
+
+	q, errs := parse("page=3&page_size=1000")
+	assert.Empty(t, errs)
+	assert.Equal(t, "SELECT * FROM `users` LIMIT 1000 OFFSET 2000", toSQL(db, q))
+
+	_, errs = parse("page_size=1001")
+	assert.Equal(t, []*FieldError{{Field: "page_size", Value: "1001", Reason: "page_size must not exceed 1000"}}, errs)
+
+	_, errs = parse("page=18446744073709551615&page_size=20")
+	assert.Equal(t, []*FieldError{{Field: "page", Value: "18446744073709551615", Reason: "page is too large"}}, errs)
+
+	_, errs = parse("page=461168601842738792&page_size=20")
+	assert.Equal(t, []*FieldError{{Field: "page", Value: "461168601842738792", Reason: "page is too large"}}, errs)
+
+	_, errs = parse("page_size=51", WithMaxPageSize(50))
+	assert.Equal(t, []*FieldError{{Field: "page_size", Value: "51", Reason: "page_size must not exceed 50"}}, errs)
+	q, errs = parse("page=2&page_size=50", WithMaxPageSize(50))
+	assert.Empty(t, errs)
+	assert.Equal(t, "SELECT * FROM `users` LIMIT 50 OFFSET 50", toSQL(db, q))
+
+	// 未指定 page_size 时使用默认的分页大小，不超过最大值
+	q, errs = parse("page=3")
+	assert.Empty(t, errs)
+	assert.Equal(t, "SELECT * FROM `users` LIMIT 20 OFFSET 40", toSQL(db, q))
+	q, errs = parse("age=1", WithDefaultPageSize(100))
+	assert.Empty(t, errs)
+	assert.Equal(t, "SELECT * FROM `users` WHERE `age` = 1 LIMIT 100", toSQL(db, q))
+	q, errs = parse("page=2", WithDefaultPageSize(100), WithMaxPageSize(10))
+	assert.Empty(t, errs)
+	assert.Equal(t, "SELECT * FROM `users` LIMIT 10 OFFSET 10", toSQL(db, q))
+}
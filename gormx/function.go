@@ -1,31 +1,92 @@
 package gormx
 
 import (
-	"fmt"
+	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/xyzbit/gpkg/convertor"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var (
-	Upper = UpperFunc{}
-	Lower = LowerFunc{}
+	Upper       = UpperFunc{}
+	Lower       = LowerFunc{}
+	Trim        = TrimFunc{}
+	Date        = DateFunc{}
+	Coalesce    = CoalesceFunc{}
+	JSONExtract = JSONExtractFunc{}
+
+	// 聚合函数，用于 Having
+	AggCount = AggregateFunc{name: "COUNT"}
+	AggSum   = AggregateFunc{name: "SUM"}
+	AggAvg   = AggregateFunc{name: "AVG"}
+	AggMax   = AggregateFunc{name: "MAX"}
+	AggMin   = AggregateFunc{name: "MIN"}
 )
 
+var functions = struct {
+	sync.RWMutex
+	m map[string]Function
+}{m: make(map[string]Function)}
+
+func init() {
+	for _, fc := range []Function{Upper, Lower, Trim, Date, Coalesce, JSONExtract, AggCount, AggSum, AggAvg, AggMax, AggMin} {
+		RegisterFunction(fc)
+	}
+}
+
+// Function 作用于列上的 SQL 函数
 type Function interface {
 	Name() string
-	Expression(params ...string) string
+	// Expression 返回函数表达式，params[0] 为列名，由 gorm 按数据库方言转义，其余参数含义由函数自行定义
+	Expression(params ...string) clause.Expression
+	// ConvertVal 转换与函数结果比较的字符串参数值，如 Upper 会将参数值转为大写
 	ConvertVal(v any) string
 }
 
+// RegisterFunction 注册自定义函数，可通过 LookupFunction 按名称（不区分大小写）查找，重复注册会 panic
+func RegisterFunction(fc Function) {
+	if fc == nil {
+		panic("gormx: RegisterFunction function is nil")
+	}
+
+	functions.Lock()
+	defer functions.Unlock()
+
+	name := strings.ToUpper(fc.Name())
+	if _, ok := functions.m[name]; ok {
+		panic("gormx: RegisterFunction called twice for function " + name)
+	}
+	functions.m[name] = fc
+}
+
+// unregisterFunction 移除已注册的函数，用于测试清理
+func unregisterFunction(name string) {
+	functions.Lock()
+	defer functions.Unlock()
+
+	delete(functions.m, strings.ToUpper(name))
+}
+
+// LookupFunction 按名称查找已注册的函数
+func LookupFunction(name string) (Function, bool) {
+	functions.RLock()
+	defer functions.RUnlock()
+
+	fc, ok := functions.m[strings.ToUpper(name)]
+	return fc, ok
+}
+
 type UpperFunc struct{}
 
 func (u UpperFunc) Name() string {
 	return "UPPER"
 }
 
-func (u UpperFunc) Expression(params ...string) string {
-	return fmt.Sprintf("UPPER(`%s`)", params[0])
+func (u UpperFunc) Expression(params ...string) clause.Expression {
+	return clause.Expr{SQL: "UPPER(?)", Vars: []any{clause.Column{Name: params[0]}}}
 }
 
 func (u UpperFunc) ConvertVal(v any) string {
@@ -38,10 +99,180 @@ func (l LowerFunc) Name() string {
 	return "LOWER"
 }
 
-func (l LowerFunc) Expression(params ...string) string {
-	return fmt.Sprintf("LOWER(`%s`)", params[0])
+func (l LowerFunc) Expression(params ...string) clause.Expression {
+	return clause.Expr{SQL: "LOWER(?)", Vars: []any{clause.Column{Name: params[0]}}}
 }
 
 func (l LowerFunc) ConvertVal(v any) string {
 	return strings.ToLower(convertor.ToString(v))
 }
+
+// TrimFunc 去掉列值两端的空格
+type TrimFunc struct{}
+
+func (t TrimFunc) Name() string {
+	return "TRIM"
+}
+
+func (t TrimFunc) Expression(params ...string) clause.Expression {
+	return clause.Expr{SQL: "TRIM(?)", Vars: []any{clause.Column{Name: params[0]}}}
+}
+
+func (t TrimFunc) ConvertVal(v any) string {
+	return strings.TrimSpace(convertor.ToString(v))
+}
+
+// DateFunc 取时间列的日期部分，参数值使用 2006-01-02 格式
+type DateFunc struct{}
+
+func (d DateFunc) Name() string {
+	return "DATE"
+}
+
+func (d DateFunc) Expression(params ...string) clause.Expression {
+	column := clause.Column{Name: params[0]}
+	return dialectExpr(func(dialect string) clause.Expression {
+		switch dialect {
+		case "postgres", "sqlserver":
+			return clause.Expr{SQL: "CAST(? AS DATE)", Vars: []any{column}}
+		default:
+			return clause.Expr{SQL: "DATE(?)", Vars: []any{column}}
+		}
+	})
+}
+
+func (d DateFunc) ConvertVal(v any) string {
+	return convertor.ToString(v)
+}
+
+// CoalesceFunc 列值为 NULL 时使用默认值，params[1:] 为依次尝试的默认值，作为参数绑定
+//
+//	q.EqWithFunction("nickname", "bob", gormx.Coalesce, "anonymous") // COALESCE(nickname, 'anonymous') = 'bob'
+type CoalesceFunc struct{}
+
+func (c CoalesceFunc) Name() string {
+	return "COALESCE"
+}
+
+func (c CoalesceFunc) Expression(params ...string) clause.Expression {
+	vars := []any{clause.Column{Name: params[0]}}
+	for _, p := range params[1:] {
+		vars = append(vars, p)
+	}
+	return clause.Expr{SQL: "COALESCE(?" + strings.Repeat(",?", len(params)-1) + ")", Vars: vars}
+}
+
+func (c CoalesceFunc) ConvertVal(v any) string {
+	return convertor.ToString(v)
+}
+
+// JSONExtractFunc 取 JSON 列中 params[1] 路径（如 $.user.tags[0]）的值，结果为文本
+//
+//	q.EqWithFunction("attrs", "bob", gormx.JSONExtract, "$.name")
+type JSONExtractFunc struct{}
+
+func (j JSONExtractFunc) Name() string {
+	return "JSON_EXTRACT"
+}
+
+func (j JSONExtractFunc) Expression(params ...string) clause.Expression {
+	column, path := clause.Column{Name: params[0]}, "$"
+	if len(params) > 1 {
+		path = params[1]
+	}
+
+	return dialectExpr(func(dialect string) clause.Expression {
+		switch dialect {
+		case "postgres":
+			return clause.Expr{SQL: "(? #>> ?)", Vars: []any{column, postgresJSONPath(path)}}
+		case "sqlite":
+			return clause.Expr{SQL: "JSON_EXTRACT(?, ?)", Vars: []any{column, path}}
+		case "sqlserver":
+			return clause.Expr{SQL: "JSON_VALUE(?, ?)", Vars: []any{column, path}}
+		default:
+			return clause.Expr{SQL: "JSON_UNQUOTE(JSON_EXTRACT(?, ?))", Vars: []any{column, path}}
+		}
+	})
+}
+
+func (j JSONExtractFunc) ConvertVal(v any) string {
+	return convertor.ToString(v)
+}
+
+// AggregateFunc 聚合函数，列名为 * 时生成 COUNT(*) 形式的表达式
+type AggregateFunc struct {
+	name string
+}
+
+func (a AggregateFunc) Name() string {
+	return a.name
+}
+
+func (a AggregateFunc) Expression(params ...string) clause.Expression {
+	if params[0] == "*" {
+		return clause.Expr{SQL: a.name + "(*)"}
+	}
+	return clause.Expr{SQL: a.name + "(?)", Vars: []any{clause.Column{Name: params[0]}}}
+}
+
+func (a AggregateFunc) ConvertVal(v any) string {
+	return convertor.ToString(v)
+}
+
+// postgresJSONPath 将 $.a.b[0] 转为 {a,b,0}
+func postgresJSONPath(path string) string {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+
+	var keys []string
+	for _, k := range strings.Split(path, ".") {
+		if k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return "{" + strings.Join(keys, ",") + "}"
+}
+
+// dialectExpr 生成 SQL 时按数据库方言选择表达式
+type dialectExpr func(dialect string) clause.Expression
+
+func (e dialectExpr) Build(builder clause.Builder) {
+	dialect := ""
+	if stmt, ok := builder.(*gorm.Statement); ok && stmt.DB != nil && stmt.Dialector != nil {
+		dialect = stmt.Dialector.Name()
+	}
+	e(dialect).Build(builder)
+}
+
+// funcArgs 将 value、fc 及 params 组装为 Condition.Build 的参数
+func funcArgs(fc Function, params []string, values ...any) []any {
+	args := append(values, fc)
+	for _, p := range params {
+		args = append(args, p)
+	}
+	return args
+}
+
+// convertFuncVal 使用 fc.ConvertVal 转换字符串参数值，切片中的字符串逐个转换，其他类型原样返回
+func convertFuncVal(fc Function, v any) any {
+	if fc == nil {
+		return v
+	}
+
+	switch val := v.(type) {
+	case string:
+		return fc.ConvertVal(val)
+	case []byte:
+		return v
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return v
+	}
+	values := make([]any, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		values = append(values, convertFuncVal(fc, rv.Index(i).Interface()))
+	}
+	return values
+}
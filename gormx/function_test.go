@@ -2,6 +2,10 @@ package gormx
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xyzbit/gpkg/convertor"
+	"gorm.io/gorm/clause"
 )
 
 func TestLower(t *testing.T) {
@@ -15,3 +19,102 @@ func TestUpper(t *testing.T) {
 	t.Log(Upper.Expression("a"))
 	t.Log(Upper.ConvertVal("abc"))
 }
+
+func TestFunction_SQL(t *testing.T) {
+	tests := []struct {
+		name   string
+		q      *Query
+		want   string
+		wantPG string
+	}{
+		{
+			name:   "eq upper",
+			q:      NewQuery().EqWithFunction("name", "bob", Upper),
+			want:   "SELECT * FROM `users` WHERE UPPER(`name`) = \"BOB\"",
+			wantPG: `SELECT * FROM "users" WHERE UPPER("name") = 'BOB'`,
+		},
+		{
+			name:   "in lower",
+			q:      NewQuery().InWithFunction("name", []string{"Bob", "ALICE"}, Lower),
+			want:   "SELECT * FROM `users` WHERE LOWER(`name`) IN (\"bob\",\"alice\")",
+			wantPG: `SELECT * FROM "users" WHERE LOWER("name") IN ('bob','alice')`,
+		},
+		{
+			name:   "date",
+			q:      NewQuery().GteWithFunction("created_at", "2024-01-01", Date).LtWithFunction("created_at", "2024-02-01", Date),
+			want:   "SELECT * FROM `users` WHERE DATE(`created_at`) >= \"2024-01-01\" AND DATE(`created_at`) < \"2024-02-01\"",
+			wantPG: `SELECT * FROM "users" WHERE CAST("created_at" AS DATE) >= '2024-01-01' AND CAST("created_at" AS DATE) < '2024-02-01'`,
+		},
+		{
+			name:   "coalesce",
+			q:      NewQuery().NotWithFunction("nickname", "anonymous", Coalesce, "anonymous"),
+			want:   "SELECT * FROM `users` WHERE NOT COALESCE(`nickname`,\"anonymous\") = \"anonymous\"",
+			wantPG: `SELECT * FROM "users" WHERE NOT COALESCE("nickname",'anonymous') = 'anonymous'`,
+		},
+		{
+			name:   "json extract",
+			q:      NewQuery().EqWithFunction("attrs", "go", JSONExtract, "$.tags[0]"),
+			want:   "SELECT * FROM `users` WHERE JSON_UNQUOTE(JSON_EXTRACT(`attrs`, \"$.tags[0]\")) = \"go\"",
+			wantPG: `SELECT * FROM "users" WHERE ("attrs" #>> '{tags,0}') = 'go'`,
+		},
+		{
+			name:   "between trim",
+			q:      NewQuery().BetweenWithFunction("code", " a", "b ", Trim),
+			want:   "SELECT * FROM `users` WHERE TRIM(`code`) BETWEEN \"a\" AND \"b\"",
+			wantPG: `SELECT * FROM "users" WHERE TRIM("code") BETWEEN 'a' AND 'b'`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, toSQL(newDryRunDB(t), tt.q))
+			assert.Equal(t, tt.wantPG, toSQL(newPostgresDryRunDB(t), tt.q))
+		})
+	}
+}
+
+func TestFunction_SQLite(t *testing.T) {
+	db := newSQLiteDB(t)
+
+	var got []user
+	err := NewQuery().EqWithFunction("name", "BOB", Lower).WithDB(db).Find(&got).Error
+	assert.NoError(t, err)
+	assert.Equal(t, []int{25}, ages(got))
+
+	got = nil
+	err = NewQuery().InWithFunction("name", []any{"alice", "carol"}, Upper).OrderBy("age").WithDB(db).Find(&got).Error
+	assert.NoError(t, err)
+	assert.Equal(t, []int{18, 32}, ages(got))
+
+	got = nil
+	err = NewQuery().EqWithFunction("name", "bob", JSONExtract, "$.name").WithDB(db.Table(`(SELECT '{"name":"bob"}' AS name, 1 AS age) t`)).Find(&got).Error
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, ages(got))
+}
+
+type reverseFunc struct{}
+
+func (reverseFunc) Name() string { return "reverse" }
+
+func (reverseFunc) Expression(params ...string) clause.Expression {
+	return clause.Expr{SQL: "REVERSE(?)", Vars: []any{clause.Column{Name: params[0]}}}
+}
+
+func (reverseFunc) ConvertVal(v any) string { return convertor.ToString(v) }
+
+func TestRegisterFunction(t *testing.T) {
+	fc, ok := LookupFunction("upper")
+	assert.True(t, ok)
+	assert.Equal(t, Upper, fc)
+
+	RegisterFunction(reverseFunc{})
+	t.Cleanup(func() { unregisterFunction(reverseFunc{}.Name()) })
+	fc, ok = LookupFunction("REVERSE")
+	assert.True(t, ok)
+	assert.Equal(t, reverseFunc{}, fc)
+
+	assert.Panics(t, func() { RegisterFunction(reverseFunc{}) })
+	assert.Panics(t, func() { RegisterFunction(nil) })
+
+	_, ok = LookupFunction("unknown")
+	assert.False(t, ok)
+}
@@ -0,0 +1,98 @@
+package gormx
+
+import (
+	"context"
+
+	"github.com/xyzbit/gpkg/ctxwrap"
+	"github.com/xyzbit/gpkg/threading"
+)
+
+// txHook 事务结束后执行的回调
+type txHook struct {
+	fn       func()
+	commit   bool // true 为 AfterCommit，false 为 AfterRollback
+	rollback bool // 所在的保存点已回滚
+}
+
+// AfterCommit 注册在 txCtx 中的事务提交后执行的回调
+//
+// 回调在最外层事务提交后按注册顺序执行，panic 会被 threading.RunSafe 捕获；所在的保存点回滚或事务回滚时不会执行。
+// txCtx 中不存在未结束的事务时立即执行 fn；事务不是由 Transaction 开启时无法得知提交结果，fn 被丢弃并输出警告。
+func AfterCommit(txCtx context.Context, fn func()) {
+	if !InTransaction(txCtx) {
+		threading.RunSafe(fn)
+		return
+	}
+	if state := hookState(txCtx); state != nil {
+		state.addHook(txHook{fn: fn, commit: true})
+	}
+}
+
+// AfterRollback 注册在 txCtx 中的事务回滚后执行的回调
+//
+// 回调在最外层事务结束后按注册顺序执行，panic 会被 threading.RunSafe 捕获；最外层事务回滚（包括提交失败、fc panic），
+// 或所在的保存点已回滚时执行。txCtx 中不存在未结束的事务，或事务不是由 Transaction 开启时 fn 不会被执行。
+func AfterRollback(txCtx context.Context, fn func()) {
+	if state := hookState(txCtx); state != nil {
+		state.addHook(txHook{fn: fn})
+	}
+}
+
+// hookState 返回 txCtx 中未结束的事务的状态，事务不是由 Transaction 开启时输出回调被丢弃的警告并返回 nil
+func hookState(txCtx context.Context) *txState {
+	if !InTransaction(txCtx) {
+		return nil
+	}
+	state := txStateFromContext(txCtx)
+	if state == nil {
+		tx := ctxwrap.FromGormDBContext(txCtx)
+		tx.Logger.Warn(txCtx, "gormx: transaction hook dropped, the transaction is not opened by gormx.Transaction")
+	}
+	return state
+}
+
+func (s *txState) addHook(h txHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, h)
+}
+
+// mark 返回当前已注册的回调数量，用于回滚到保存点
+func (s *txState) mark() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.hooks)
+}
+
+// rollbackTo 保存点回滚，mark 之后注册的回调视为已回滚
+func (s *txState) rollbackTo(mark int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := mark; i < len(s.hooks); i++ {
+		s.hooks[i].rollback = true
+	}
+}
+
+// finish 最外层事务结束后按注册顺序执行回调
+func (s *txState) finish(committed bool) {
+	s.mu.Lock()
+	hooks := s.hooks
+	s.hooks = nil
+	s.mu.Unlock()
+
+	for _, h := range hooks {
+		run := committed && !h.rollback
+		if !h.commit {
+			run = !run
+		}
+		if run {
+			threading.RunSafe(h.fn)
+		}
+	}
+}
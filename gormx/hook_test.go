@@ -0,0 +1,112 @@
+package gormx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xyzbit/gpkg/ctxwrap"
+	"gorm.io/gorm/logger"
+)
+
+func TestAfterCommit(t *testing.T) {
+	db := newTxDB(t)
+	maker := NewDBMaker(db)
+	ctx := context.Background()
+
+	var calls []string
+	record := func(name string) func() {
+		return func() { calls = append(calls, name) }
+	}
+
+	err := Transaction(ctx, maker, func(txCtx context.Context) error {
+		AfterCommit(txCtx, record("commit1"))
+		AfterRollback(txCtx, record("rollback1"))
+		AfterCommit(txCtx, func() { panic("boom") })
+		err := Transaction(txCtx, maker, func(innerCtx context.Context) error {
+			AfterCommit(innerCtx, record("commit2"))
+			return nil
+		})
+		assert.Empty(t, calls)
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"commit1", "commit2"}, calls)
+
+	calls = nil
+	err = Transaction(ctx, maker, func(txCtx context.Context) error {
+		AfterCommit(txCtx, record("commit"))
+		AfterRollback(txCtx, record("rollback"))
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+	assert.Equal(t, []string{"rollback"}, calls)
+
+	calls = nil
+	assert.Panics(t, func() {
+		_ = Transaction(ctx, maker, func(txCtx context.Context) error {
+			AfterCommit(txCtx, record("commit"))
+			AfterRollback(txCtx, record("rollback"))
+			panic("boom")
+		})
+	})
+	assert.Equal(t, []string{"rollback"}, calls)
+}
+
+func TestAfterCommit_Savepoint(t *testing.T) {
+	db := newTxDB(t)
+	maker := NewDBMaker(db)
+
+	var calls []string
+	record := func(name string) func() {
+		return func() { calls = append(calls, name) }
+	}
+
+	err := Transaction(context.Background(), maker, func(txCtx context.Context) error {
+		AfterCommit(txCtx, record("outer"))
+		_ = Transaction(txCtx, maker, func(innerCtx context.Context) error {
+			AfterCommit(innerCtx, record("inner commit"))
+			AfterRollback(innerCtx, record("inner rollback"))
+			return errRollback
+		}, WithPropagation(PropagationNested))
+		return Transaction(txCtx, maker, func(innerCtx context.Context) error {
+			AfterCommit(innerCtx, record("nested commit"))
+			return nil
+		}, WithPropagation(PropagationNested))
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"outer", "inner rollback", "nested commit"}, calls)
+}
+
+func TestAfterCommit_WithoutTransaction(t *testing.T) {
+	ctx := context.Background()
+
+	var calls []string
+	AfterCommit(ctx, func() { calls = append(calls, "commit") })
+	AfterRollback(ctx, func() { calls = append(calls, "rollback") })
+	assert.Equal(t, []string{"commit"}, calls)
+
+	var leaked context.Context
+	assert.NoError(t, Transaction(ctx, NewDBMaker(newTxDB(t)), func(txCtx context.Context) error {
+		leaked = txCtx
+		return nil
+	}))
+	AfterCommit(leaked, func() { calls = append(calls, "leaked") })
+	assert.Equal(t, []string{"commit", "leaked"}, calls)
+}
+
+func TestAfterCommit_ForeignTransaction(t *testing.T) {
+	db := newTxDB(t)
+	log := &warnLogger{Interface: logger.Discard}
+	db.Logger = log
+
+	// 未通过 Transaction 开启的事务无法得知提交结果，回调被丢弃
+	tx := db.Begin()
+	txCtx := ctxwrap.NewGormDBContext(context.Background(), tx)
+	var calls []string
+	AfterCommit(txCtx, func() { calls = append(calls, "commit") })
+	AfterRollback(txCtx, func() { calls = append(calls, "rollback") })
+	assert.NoError(t, tx.Rollback().Error)
+	assert.Empty(t, calls)
+	assert.Len(t, log.warnings, 2)
+}
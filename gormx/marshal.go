@@ -0,0 +1,717 @@
+package gormx
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/pkg/errors"
+	"gorm.io/gorm/schema"
+)
+
+// QueryVersion Query 序列化格式的当前版本
+const QueryVersion = 1
+
+// ErrInvalidQuery 反序列化得到的 Query 不合法
+var ErrInvalidQuery = errors.New("invalid query")
+
+// queryAST Query 序列化后的结构
+//
+//	{"version":1,"conditions":[{"kind":"Equal","column":"name","values":[{"t":"s","v":"bob"}]}]}
+type queryAST struct {
+	Version    int         `json:"version"`
+	Conditions []queryNode `json:"conditions"`
+}
+
+// queryNode 单个条件，各字段的含义由 Kind 决定：
+//
+//	Equal、Not、In、NotIn、Gt、Gte、Lt、Lte、Between  column、values、func、params
+//	Like                                            column、op（contains|prefix|suffix|pattern）、values、not、insensitive、func、params
+//	Or                                              column 为列名、values，生成 column = value
+//	IsNull、NotNull                                  column
+//	AndGroup、OrGroup                                groups，每个分组为一组条件
+//	Cursor                                          columns 为排序字段、values 为游标及每页条数
+//	OrderBy、CustomOrder                             column、op（asc|desc）、nulls（first|last）
+//	Limit、Offset                                    values
+//	Select、Group、Distinct                           columns
+//	Joins、Preload                                   column 为关联名，groups 中为一组关联表上的条件
+//	Having                                          groups
+//	Deleted                                         op（with|only|without）
+//
+// InSubQuery、NotInSubQuery 的子查询绑定了 Go 模型，Scopes 为 Go 函数，均无法序列化；
+// Or 的原始表达式、原始排序表达式、JOIN 子句及关联的条件参数为原始 SQL，同样无法序列化。
+type queryNode struct {
+	Kind        Kind          `json:"kind"`
+	Column      string        `json:"column,omitempty"`
+	Op          string        `json:"op,omitempty"`
+	Values      []typedValue  `json:"values,omitempty"`
+	Columns     []string      `json:"columns,omitempty"`
+	Func        string        `json:"func,omitempty"`
+	Params      []string      `json:"params,omitempty"`
+	Not         bool          `json:"not,omitempty"`
+	Insensitive bool          `json:"insensitive,omitempty"`
+	Nulls       string        `json:"nulls,omitempty"`
+	Groups      [][]queryNode `json:"groups,omitempty"`
+}
+
+// typedValue 带类型的值，值统一编码为字符串，避免 JSON 数字丢失精度
+type typedValue struct {
+	Type  string       `json:"t"`
+	Value string       `json:"v,omitempty"`
+	Items []typedValue `json:"a,omitempty"`
+}
+
+// relationNameRegexp 关联名，嵌套的关联以 . 分隔，如 Orders.Items
+var relationNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+var deletedModeNames = map[deletedMode]string{
+	deletedExclude: "without",
+	deletedInclude: "with",
+	deletedOnly:    "only",
+}
+
+var likeModeNames = map[likeMode]string{
+	likeContains: "contains",
+	likePrefix:   "prefix",
+	likeSuffix:   "suffix",
+	likePattern:  "pattern",
+}
+
+// MarshalJSON 将 Query 的条件序列化为带版本号的 JSON，绑定的 Model 及允许的列不会被序列化
+func (q *Query) MarshalJSON() ([]byte, error) {
+	nodes, err := q.nodes()
+	if err != nil {
+		return nil, err
+	}
+	// 与解析使用相同的校验，原始 SQL 表达式无法序列化
+	if err := NewQuery().applyNodes(nodes); err != nil {
+		return nil, err
+	}
+	return sonic.Marshal(queryAST{Version: QueryVersion, Conditions: nodes})
+}
+
+// UnmarshalJSON 解析 MarshalJSON 生成的 JSON 并校验，替换 q 中已有的条件，保留 q 绑定的 Model 及允许的列
+//
+// JSON 通常来自不可信的来源，解析时只接受列名及关联名，Or 的原始表达式、原始排序表达式、JOIN 子句及关联的条件参数均被拒绝；
+// q 开启了校验模式时，还会按 Model 及 AllowColumns 校验引用的列，Model 按 gorm 默认的命名策略解析。
+func (q *Query) UnmarshalJSON(b []byte) error {
+	var ast queryAST
+	if err := sonic.Unmarshal(b, &ast); err != nil {
+		return errors.Wrap(ErrInvalidQuery, err.Error())
+	}
+	if ast.Version <= 0 || ast.Version > QueryVersion {
+		return errors.Wrapf(ErrInvalidQuery, "unsupported version %d", ast.Version)
+	}
+
+	decoded := NewQuery()
+	if err := decoded.applyNodes(ast.Conditions); err != nil {
+		return err
+	}
+	decoded.model, decoded.allowed = q.model, q.allowed
+	if err := decoded.validateWith(parseDecodeSchema); err != nil {
+		// 同时满足 errors.Is(err, ErrInvalidQuery) 及 errors.Is(err, ErrUnknownColumn)
+		return fmt.Errorf("%w: %w", ErrInvalidQuery, err)
+	}
+	q.conMap = decoded.conMap
+	return nil
+}
+
+// decodeSchemaCache 解析 JSON 时校验模型使用的 schema 缓存
+var decodeSchemaCache sync.Map
+
+func parseDecodeSchema(model any) (*schema.Schema, error) {
+	return schema.Parse(model, &decodeSchemaCache, schema.NamingStrategy{})
+}
+
+// String 返回便于调试的条件描述，如 Equal(name "bob") Gt(age 18) OrderBy(age desc)
+func (q *Query) String() string {
+	nodes, err := q.nodes()
+	if err != nil {
+		return "Query(" + err.Error() + ")"
+	}
+	return formatNodes(nodes)
+}
+
+func (q *Query) nodes() ([]queryNode, error) {
+	var nodes []queryNode
+	for _, k := range execOrder {
+		cond, ok := q.conMap[k]
+		if !ok {
+			continue
+		}
+		n, err := condNodes(k, cond)
+		if err != nil {
+			return nil, errors.WithMessage(err, string(k))
+		}
+		nodes = append(nodes, n...)
+	}
+	return nodes, nil
+}
+
+func condNodes(kind Kind, cond Condition) ([]queryNode, error) {
+	switch c := cond.(type) {
+	case *equalCond:
+		return itemNodes(kind, c.body)
+	case *notCond:
+		return itemNodes(kind, c.body)
+	case *inCond:
+		return itemNodes(kind, c.body)
+	case *notInCond:
+		return itemNodes(kind, c.body)
+	case *gtCond:
+		return itemNodes(kind, c.body)
+	case *gteCond:
+		return itemNodes(kind, c.body)
+	case *ltCond:
+		return itemNodes(kind, c.body)
+	case *lteCond:
+		return itemNodes(kind, c.body)
+	case *betweenCond:
+		return itemNodes(kind, c.body)
+	case *orCond:
+		return itemNodes(kind, c.body)
+	case *likeCond:
+		nodes := make([]queryNode, 0, len(c.body))
+		for _, lv := range c.body {
+			v, err := encodeTypedValue(lv.val)
+			if err != nil {
+				return nil, err
+			}
+			n := queryNode{
+				Kind:        kind,
+				Column:      lv.key,
+				Op:          likeModeNames[lv.mode],
+				Values:      []typedValue{v},
+				Params:      lv.params,
+				Not:         lv.not,
+				Insensitive: lv.insensitive,
+			}
+			if lv.fc != nil {
+				n.Func = lv.fc.Name()
+			}
+			nodes = append(nodes, n)
+		}
+		return nodes, nil
+	case *isNullCond:
+		return columnNodes(kind, c.body), nil
+	case *notNullCond:
+		return columnNodes(kind, c.body), nil
+	case *nestedCond:
+		n := queryNode{Kind: kind}
+		for _, sub := range c.body {
+			group, err := sub.nodes()
+			if err != nil {
+				return nil, err
+			}
+			n.Groups = append(n.Groups, group)
+		}
+		return []queryNode{n}, nil
+	case *cursorCond:
+		return []queryNode{{
+			Kind:    kind,
+			Columns: c.orderBy,
+			Values:  []typedValue{{Type: "s", Value: c.cursor}, {Type: "i", Value: strconv.Itoa(c.size)}},
+		}}, nil
+	case *orderByCond:
+		return orderNodes(kind, c.body), nil
+	case *customOrderCond:
+		return orderNodes(kind, c.items()), nil
+	case *deletedCond:
+		return []queryNode{{Kind: kind, Op: deletedModeNames[c.mode]}}, nil
+	case *limitCond:
+		return []queryNode{{Kind: kind, Values: []typedValue{{Type: "i", Value: strconv.Itoa(c.body)}}}}, nil
+	case *offsetCond:
+		return []queryNode{{Kind: kind, Values: []typedValue{{Type: "i", Value: strconv.Itoa(c.body)}}}}, nil
+	case *selectCond:
+		return []queryNode{{Kind: kind, Columns: c.fields}}, nil
+	case *distinctCond:
+		return []queryNode{{Kind: kind, Columns: c.fields}}, nil
+	case *joinsCond:
+		return relationNodes(kind, c.body)
+	case *preloadCond:
+		return relationNodes(kind, c.body)
+	case *havingCond:
+		n := queryNode{Kind: kind}
+		for _, sub := range c.body {
+			group, err := sub.nodes()
+			if err != nil {
+				return nil, err
+			}
+			n.Groups = append(n.Groups, group)
+		}
+		return []queryNode{n}, nil
+	case *groupCond:
+		return []queryNode{{Kind: kind, Columns: c.fields}}, nil
+	case *subQueryCond:
+		return nil, errors.New("subquery is not serializable")
+	case *scopesCond:
+		return nil, errors.New("scopes are not serializable")
+	default:
+		return nil, errors.Errorf("unsupported condition %T", cond)
+	}
+}
+
+func itemNodes(kind Kind, items []condItem) ([]queryNode, error) {
+	nodes := make([]queryNode, 0, len(items))
+	for _, item := range items {
+		n := queryNode{Kind: kind, Column: item.key, Params: item.params}
+		if item.fc != nil {
+			n.Func = item.fc.Name()
+		}
+		for _, arg := range item.args {
+			v, err := encodeTypedValue(arg)
+			if err != nil {
+				return nil, errors.WithMessage(err, item.key)
+			}
+			n.Values = append(n.Values, v)
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+func relationNodes(kind Kind, items []relationItem) ([]queryNode, error) {
+	nodes := make([]queryNode, 0, len(items))
+	for _, item := range items {
+		n := queryNode{Kind: kind, Column: item.name}
+		if item.sub != nil {
+			group, err := item.sub.nodes()
+			if err != nil {
+				return nil, err
+			}
+			n.Groups = [][]queryNode{group}
+		}
+		for _, arg := range item.args {
+			v, err := encodeTypedValue(arg)
+			if err != nil {
+				return nil, errors.WithMessage(err, item.name)
+			}
+			n.Values = append(n.Values, v)
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+func columnNodes(kind Kind, columns []string) []queryNode {
+	nodes := make([]queryNode, 0, len(columns))
+	for _, c := range columns {
+		nodes = append(nodes, queryNode{Kind: kind, Column: c})
+	}
+	return nodes
+}
+
+// orderNodes 不合法的排序项不影响生成的 SQL，不会被序列化
+func orderNodes(kind Kind, items []orderItem) []queryNode {
+	nodes := make([]queryNode, 0, len(items))
+	for _, item := range items {
+		switch {
+		case item.invalid != "":
+		case item.raw != "":
+			nodes = append(nodes, queryNode{Kind: kind, Column: item.raw, Op: "raw"})
+		default:
+			n := queryNode{Kind: kind, Column: item.field.Column, Op: "asc"}
+			if item.field.Desc {
+				n.Op = "desc"
+			}
+			switch item.field.Nulls {
+			case NullsFirst:
+				n.Nulls = "first"
+			case NullsLast:
+				n.Nulls = "last"
+			}
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+func (q *Query) applyNodes(nodes []queryNode) error {
+	for _, n := range nodes {
+		if err := q.applyNode(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q *Query) applyNode(n queryNode) error {
+	invalid := func(format string, args ...any) error {
+		return errors.Wrapf(ErrInvalidQuery, "%s: "+format, append([]any{n.Kind}, args...)...)
+	}
+
+	switch n.Kind {
+	case kindSelect, kindGroup:
+		if len(n.Columns) == 0 {
+			return invalid("columns required")
+		}
+		if c, ok := invalidColumn(n.Columns...); !ok {
+			return invalid("invalid column %q", c)
+		}
+		fields := make([]any, 0, len(n.Columns))
+		for _, c := range n.Columns {
+			fields = append(fields, c)
+		}
+		if n.Kind == kindSelect {
+			q.Select(fields...)
+		} else {
+			q.Group(fields...)
+		}
+		return nil
+	case kindAndGroup, kindOrGroup, kindHaving:
+		for _, group := range n.Groups {
+			var err error
+			fn := func(sub *Query) { err = sub.applyNodes(group) }
+			switch n.Kind {
+			case kindAndGroup:
+				q.AndGroup(fn)
+			case kindOrGroup:
+				q.OrGroup(fn)
+			default:
+				q.Having(fn)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case kindDistinct:
+		if c, ok := invalidColumn(n.Columns...); !ok {
+			return invalid("invalid column %q", c)
+		}
+		fields := make([]any, 0, len(n.Columns))
+		for _, c := range n.Columns {
+			fields = append(fields, c)
+		}
+		q.Distinct(fields...)
+		return nil
+	case kindJoins, kindPreload:
+		return q.applyRelationNode(n)
+	case kindDeleted:
+		for mode, name := range deletedModeNames {
+			if name == n.Op {
+				q.deleted(mode)
+				return nil
+			}
+		}
+		return invalid("unknown op %q", n.Op)
+	case kindOr:
+		// Or 的 column 只能为列名，生成 column = value
+		if !columnNameRegexp.MatchString(n.Column) {
+			return invalid("invalid column %q", n.Column)
+		}
+	case kindEqual, kindNot, kindIn, kindNotIn, kindGt, kindGte, kindLt, kindLte, kindBetween, kindLike,
+		kindIsNull, kindNotNull, kindOrderBy, kindCustomOrder:
+		if c, ok := invalidColumn(n.Column); !ok {
+			return invalid("invalid column %q", c)
+		}
+	case kindCursor, kindLimit, kindOffset:
+	case kindInSubQuery, kindNotInSubQuery, kindScopes:
+		return invalid("not serializable")
+	default:
+		return invalid("unknown kind")
+	}
+
+	values := make([]any, 0, len(n.Values))
+	for _, tv := range n.Values {
+		v, err := decodeTypedValue(tv)
+		if err != nil {
+			return invalid("%v", err)
+		}
+		values = append(values, v)
+	}
+	wantValues := map[Kind]int{kindBetween: 2, kindCursor: 2, kindIsNull: 0, kindNotNull: 0, kindOrderBy: 0, kindCustomOrder: 0}
+	want, ok := wantValues[n.Kind]
+	if !ok {
+		want = 1
+	}
+	if len(values) != want {
+		return invalid("want %d values, got %d", want, len(values))
+	}
+
+	var fc Function
+	if n.Func != "" {
+		if fc, ok = LookupFunction(n.Func); !ok {
+			return invalid("unknown function %q", n.Func)
+		}
+	}
+
+	switch n.Kind {
+	case kindEqual, kindNot, kindIn, kindNotIn, kindGt, kindGte, kindLt, kindLte, kindBetween:
+		args := values
+		if fc != nil {
+			args = funcArgs(fc, n.Params, values...)
+		}
+		cond, ok := q.conMap[n.Kind]
+		if !ok {
+			cond = newCondition(n.Kind)
+			q.conMap[n.Kind] = cond
+		}
+		cond.Build(n.Column, args...)
+	case kindLike:
+		lv := likeVal{key: n.Column, val: values[0], fc: fc, params: n.Params, not: n.Not, insensitive: n.Insensitive}
+		found := false
+		for mode, name := range likeModeNames {
+			if n.Op == name {
+				lv.mode, found = mode, true
+			}
+		}
+		if !found {
+			return invalid("unknown like op %q", n.Op)
+		}
+		q.like(lv)
+	case kindOr:
+		q.Or(n.Column, values[0])
+	case kindIsNull:
+		q.IsNull(n.Column)
+	case kindNotNull:
+		q.NotNull(n.Column)
+	case kindCursor:
+		cursor, ok1 := values[0].(string)
+		size, ok2 := values[1].(int64)
+		if !ok1 || !ok2 {
+			return invalid("want cursor and size")
+		}
+		q.Cursor(cursor, int(size), n.Columns...)
+		if err := q.conMap[kindCursor].(*cursorCond).err; err != nil {
+			return errors.Wrap(ErrInvalidQuery, err.Error())
+		}
+	case kindOrderBy, kindCustomOrder:
+		item := orderItem{field: SortField{Column: n.Column}}
+		switch n.Op {
+		case "", "asc":
+		case "desc":
+			item.field.Desc = true
+		default:
+			return invalid("unknown order %q", n.Op)
+		}
+		switch n.Nulls {
+		case "":
+		case "first":
+			item.field.Nulls = NullsFirst
+		case "last":
+			item.field.Nulls = NullsLast
+		default:
+			return invalid("unknown nulls %q", n.Nulls)
+		}
+
+		if n.Kind == kindOrderBy {
+			q.addOrder(item)
+			return nil
+		}
+		cond, ok := q.conMap[kindCustomOrder].(*customOrderCond)
+		if !ok {
+			cond = newCustomOrderCond()
+			q.conMap[kindCustomOrder] = cond
+		}
+		cond.order = append(cond.order, item)
+	case kindLimit, kindOffset:
+		v, ok := values[0].(int64)
+		if !ok {
+			return invalid("want integer")
+		}
+		if n.Kind == kindLimit {
+			q.Limit(int(v))
+		} else {
+			q.Offset(int(v))
+		}
+	}
+	return nil
+}
+
+// applyRelationNode 只允许关联名，关联上的条件通过 groups 传递，原始的 JOIN 子句及条件参数会被拒绝
+func (q *Query) applyRelationNode(n queryNode) error {
+	if !relationNameRegexp.MatchString(n.Column) || len(n.Values) > 0 || len(n.Groups) > 1 {
+		return errors.Wrapf(ErrInvalidQuery, "%s: invalid relation %q", n.Kind, n.Column)
+	}
+
+	var args []any
+	var err error
+	if len(n.Groups) == 1 {
+		args = []any{func(sub *Query) { err = sub.applyNodes(n.Groups[0]) }}
+	}
+	if n.Kind == kindJoins {
+		q.Joins(n.Column, args...)
+	} else {
+		q.Preload(n.Column, args...)
+	}
+	return err
+}
+
+// invalidColumn 返回 columns 中第一个不是列名的值
+func invalidColumn(columns ...string) (string, bool) {
+	for _, c := range columns {
+		if c != "*" && !columnNameRegexp.MatchString(c) {
+			return c, false
+		}
+	}
+	return "", true
+}
+
+func newCondition(kind Kind) Condition {
+	switch kind {
+	case kindEqual:
+		return newEqualCond()
+	case kindNot:
+		return newNotCond()
+	case kindIn:
+		return newInCond()
+	case kindNotIn:
+		return newNotInCond()
+	case kindGt:
+		return newGtCond()
+	case kindGte:
+		return newGteCond()
+	case kindLt:
+		return newLtCond()
+	case kindLte:
+		return newLteCond()
+	default:
+		return newBetweenCond()
+	}
+}
+
+func formatNodes(nodes []queryNode) string {
+	parts := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		parts = append(parts, n.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+func (n queryNode) String() string {
+	var parts []string
+	switch {
+	case n.Func != "":
+		parts = append(parts, n.Func+"("+strings.Join(append([]string{n.Column}, n.Params...), ", ")+")")
+	case n.Column != "":
+		parts = append(parts, n.Column)
+	}
+	if n.Not {
+		parts = append(parts, "not")
+	}
+	if n.Insensitive {
+		parts = append(parts, "insensitive")
+	}
+	if n.Op != "" {
+		parts = append(parts, n.Op)
+	}
+	if n.Nulls != "" {
+		parts = append(parts, "nulls "+n.Nulls)
+	}
+	if len(n.Columns) > 0 {
+		parts = append(parts, strings.Join(n.Columns, ", "))
+	}
+	for _, v := range n.Values {
+		parts = append(parts, v.String())
+	}
+	for _, g := range n.Groups {
+		parts = append(parts, "("+formatNodes(g)+")")
+	}
+	return string(n.Kind) + "(" + strings.Join(parts, " ") + ")"
+}
+
+func (v typedValue) String() string {
+	switch v.Type {
+	case "n":
+		return "NULL"
+	case "s":
+		return strconv.Quote(v.Value)
+	case "a":
+		items := make([]string, 0, len(v.Items))
+		for _, item := range v.Items {
+			items = append(items, item.String())
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	default:
+		return v.Value
+	}
+}
+
+func encodeTypedValue(v any) (typedValue, error) {
+	if valuer, ok := v.(driver.Valuer); ok {
+		var err error
+		if v, err = valuer.Value(); err != nil {
+			return typedValue{}, err
+		}
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return typedValue{Type: "n"}, nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return typedValue{Type: "n"}, nil
+	}
+
+	if t, ok := rv.Interface().(time.Time); ok {
+		return typedValue{Type: "t", Value: t.Format(time.RFC3339Nano)}, nil
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return typedValue{Type: "i", Value: strconv.FormatInt(rv.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return typedValue{Type: "u", Value: strconv.FormatUint(rv.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		return typedValue{Type: "f", Value: strconv.FormatFloat(rv.Float(), 'g', -1, 64)}, nil
+	case reflect.Bool:
+		return typedValue{Type: "b", Value: strconv.FormatBool(rv.Bool())}, nil
+	case reflect.String:
+		return typedValue{Type: "s", Value: rv.String()}, nil
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return typedValue{Type: "s", Value: string(rv.Bytes())}, nil
+		}
+		tv := typedValue{Type: "a", Items: make([]typedValue, 0, rv.Len())}
+		for i := 0; i < rv.Len(); i++ {
+			item, err := encodeTypedValue(rv.Index(i).Interface())
+			if err != nil {
+				return typedValue{}, err
+			}
+			tv.Items = append(tv.Items, item)
+		}
+		return tv, nil
+	default:
+		return typedValue{}, errors.Errorf("unsupported value type %s", rv.Type())
+	}
+}
+
+func decodeTypedValue(tv typedValue) (any, error) {
+	switch tv.Type {
+	case "n":
+		return nil, nil
+	case "t":
+		return time.Parse(time.RFC3339Nano, tv.Value)
+	case "i":
+		return strconv.ParseInt(tv.Value, 10, 64)
+	case "u":
+		return strconv.ParseUint(tv.Value, 10, 64)
+	case "f":
+		return strconv.ParseFloat(tv.Value, 64)
+	case "b":
+		return strconv.ParseBool(tv.Value)
+	case "s":
+		return tv.Value, nil
+	case "a":
+		items := make([]any, 0, len(tv.Items))
+		for _, item := range tv.Items {
+			v, err := decodeTypedValue(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, nil
+	default:
+		return nil, errors.Errorf("unknown value type %q", tv.Type)
+	}
+}
@@ -0,0 +1,184 @@
+package gormx
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery_JSONRoundTrip(t *testing.T) {
+	cursor, err := EncodeCursor(&user{ID: 7, Age: 20}, "age desc", "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queries := map[string]*Query{
+		"conditions": NewQuery().
+			Select("id", "name").
+			Eq("name", "bob").
+			EqWithFunction("name", "BOB", Upper).
+			Not("age", int8(3)).
+			In("id", []int64{1, 2}).
+			NotIn("name", []string{"a"}).
+			Gt("age", 1.5).
+			Gte("created_at", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)).
+			Lt("age", uint(90)).
+			Lte("score", nil).
+			Between("age", 10, 30).
+			LikePrefix("name", "b%").
+			ILike("name", "O").
+			LikeWithFunction("attrs", "go", JSONExtract, "$.lang").
+			IsNull("deleted_at").
+			NotNull("name").
+			Or("name", "alice"),
+		"groups": NewQuery().Eq("a", true).OrGroup(func(sub *Query) {
+			sub.Eq("b", 2).AndGroup(func(sub *Query) {
+				sub.Lt("c", 3)
+			})
+		}),
+		"order and page": NewQuery().
+			Group("name").
+			OrderBy("age desc nulls last").
+			CustomOrder(`[{"field":"name"}]`, "id desc").
+			Limit(10).
+			Offset(20),
+		"cursor": NewQuery().Cursor(cursor, 10, "age desc", "id"),
+	}
+
+	for name, q := range queries {
+		t.Run(name, func(t *testing.T) {
+			b, err := json.Marshal(q)
+			assert.NoError(t, err)
+
+			got := NewQuery()
+			assert.NoError(t, json.Unmarshal(b, got))
+			assert.Equal(t, toSQL(newDryRunDB(t), q), toSQL(newDryRunDB(t), got))
+			assert.Equal(t, q.String(), got.String())
+
+			again, err := json.Marshal(got)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(b), string(again))
+		})
+	}
+}
+
+func TestQuery_JSONRoundTripRelations(t *testing.T) {
+	q := NewQuery().
+		Distinct("name").
+		Joins("Orders").
+		JoinsWith("Company", func(sub *Query) { sub.Eq("Company.alive", true) }).
+		PreloadWith("Orders", func(sub *Query) { sub.Gt("amount", 1) }).
+		Group("name").
+		Having(func(sub *Query) { sub.GtWithFunction("*", 1, AggCount) })
+
+	b, err := json.Marshal(q)
+	assert.NoError(t, err)
+
+	got := NewQuery()
+	assert.NoError(t, json.Unmarshal(b, got))
+	assert.Equal(t, q.String(), got.String())
+
+	again, err := json.Marshal(got)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(b), string(again))
+}
+
+func TestQuery_MarshalJSON(t *testing.T) {
+	b, err := json.Marshal(NewQuery().Eq("name", "bob").In("id", []int{1, 2}).OrderBy("id desc").Limit(10))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"version":1,"conditions":[
+		{"kind":"Equal","column":"name","values":[{"t":"s","v":"bob"}]},
+		{"kind":"In","column":"id","values":[{"t":"a","a":[{"t":"i","v":"1"},{"t":"i","v":"2"}]}]},
+		{"kind":"OrderBy","column":"id","op":"desc"},
+		{"kind":"Limit","values":[{"t":"i","v":"10"}]}
+	]}`, string(b))
+
+	_, err = json.Marshal(NewQuery().Eq("name", struct{}{}))
+	assert.Error(t, err)
+
+	_, err = json.Marshal(NewQuery().InSubQuery("id", NewQuery().Model(&user{}).Select("id")))
+	assert.Error(t, err)
+
+	raw := map[string]*Query{
+		"or":      NewQuery().Or("name = ?", "alice"),
+		"order":   NewQuery().OrderBy("FIELD(id, 3, 1)"),
+		"join":    NewQuery().Joins("JOIN orders ON orders.user_id = users.id"),
+		"preload": NewQuery().Preload("Orders", "amount > ?", 1),
+		"select":  NewQuery().Select("COUNT(*) AS n"),
+	}
+	for name, q := range raw {
+		_, err = json.Marshal(q)
+		assert.True(t, errors.Is(err, ErrInvalidQuery), name, err)
+	}
+}
+
+func TestQuery_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "missing version", data: `{"conditions":[]}`},
+		{name: "newer version", data: `{"version":2,"conditions":[]}`},
+		{name: "unknown kind", data: `{"version":1,"conditions":[{"kind":"Drop"}]}`},
+		{name: "invalid column", data: `{"version":1,"conditions":[{"kind":"Equal","column":"a;b","values":[{"t":"i","v":"1"}]}]}`},
+		{name: "missing value", data: `{"version":1,"conditions":[{"kind":"Gt","column":"age"}]}`},
+		{name: "bad value", data: `{"version":1,"conditions":[{"kind":"Gt","column":"age","values":[{"t":"i","v":"x"}]}]}`},
+		{name: "unknown function", data: `{"version":1,"conditions":[{"kind":"Equal","column":"a","func":"SLEEP","values":[{"t":"i","v":"1"}]}]}`},
+		{name: "unknown like op", data: `{"version":1,"conditions":[{"kind":"Like","column":"a","op":"regexp","values":[{"t":"s","v":"1"}]}]}`},
+		{name: "invalid group", data: `{"version":1,"conditions":[{"kind":"AndGroup","groups":[[{"kind":"IsNull","column":"1=1 OR a"}]]}]}`},
+		{name: "invalid cursor", data: `{"version":1,"conditions":[{"kind":"Cursor","values":[{"t":"s","v":"!"},{"t":"i","v":"10"}]}]}`},
+		{name: "malformed", data: `{"version":1,"conditions":{}}`},
+		{name: "raw join", data: `{"version":1,"conditions":[{"kind":"Joins","column":"LEFT JOIN sqlite_master s ON 1=1"}]}`},
+		{name: "join values", data: `{"version":1,"conditions":[{"kind":"Joins","column":"Company","values":[{"t":"s","v":"1=1"}]}]}`},
+		{name: "preload values", data: `{"version":1,"conditions":[{"kind":"Preload","column":"Orders","values":[{"t":"s","v":"1=1"}]}]}`},
+		{name: "invalid join group", data: `{"version":1,"conditions":[{"kind":"Joins","column":"Company","groups":[[{"kind":"IsNull","column":"1=1 OR a"}]]}]}`},
+		{name: "raw or", data: `{"version":1,"conditions":[{"kind":"Or","column":"1=1 OR name = ?","values":[{"t":"s","v":"a"}]}]}`},
+		{name: "raw order", data: `{"version":1,"conditions":[{"kind":"OrderBy","column":"(SELECT 1)","op":"raw"}]}`},
+		{name: "raw order column", data: `{"version":1,"conditions":[{"kind":"OrderBy","column":"(SELECT 1)"}]}`},
+		{name: "select expression", data: `{"version":1,"conditions":[{"kind":"Select","columns":["id","(SELECT sql FROM sqlite_master)"]}]}`},
+		{name: "group expression", data: `{"version":1,"conditions":[{"kind":"Group","columns":["1; DROP TABLE users"]}]}`},
+		{name: "distinct expression", data: `{"version":1,"conditions":[{"kind":"Distinct","columns":["a,b"]}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := json.Unmarshal([]byte(tt.data), NewQuery())
+			assert.True(t, errors.Is(err, ErrInvalidQuery), err)
+		})
+	}
+
+	// 校验模式下解析时按 Model 及允许的列校验，失败时保留原有的条件
+	q := NewQuery().Model(&user{}).Eq("name", "bob")
+	err := json.Unmarshal([]byte(`{"version":1,"conditions":[{"kind":"Equal","column":"unknown","values":[{"t":"i","v":"1"}]}]}`), q)
+	assert.True(t, errors.Is(err, ErrInvalidQuery), err)
+	assert.True(t, errors.Is(err, ErrUnknownColumn), err)
+	assert.Equal(t, `Equal(name "bob")`, q.String())
+
+	q = NewQuery().AllowColumns("name")
+	err = json.Unmarshal([]byte(`{"version":1,"conditions":[{"kind":"Select","columns":["password"]}]}`), q)
+	assert.True(t, errors.Is(err, ErrUnknownColumn), err)
+	assert.NoError(t, json.Unmarshal([]byte(`{"version":1,"conditions":[{"kind":"Select","columns":["name"]}]}`), q))
+
+	q = NewQuery().Model(&employee{})
+	assert.NoError(t, json.Unmarshal([]byte(`{"version":1,"conditions":[{"kind":"Joins","column":"Company",`+
+		`"groups":[[{"kind":"Equal","column":"Company.alive","values":[{"t":"b","v":"true"}]}]]}]}`), q))
+	err = json.Unmarshal([]byte(`{"version":1,"conditions":[{"kind":"Joins","column":"Company",`+
+		`"groups":[[{"kind":"Equal","column":"Company.secret","values":[{"t":"b","v":"true"}]}]]}]}`), q)
+	assert.True(t, errors.Is(err, ErrUnknownColumn), err)
+	err = json.Unmarshal([]byte(`{"version":1,"conditions":[{"kind":"Preload","column":"Manager"}]}`), q)
+	assert.True(t, errors.Is(err, ErrUnknownColumn), err)
+}
+
+func TestQuery_String(t *testing.T) {
+	q := NewQuery().
+		Eq("name", "bob").
+		InWithFunction("name", []string{"a", "b"}, Coalesce, "x").
+		OrGroup(func(sub *Query) { sub.Gt("age", 18).IsNull("deleted_at") }).
+		OrderBy("age desc").
+		Limit(10)
+	want := `Equal(name "bob") In(COALESCE(name, x) ["a", "b"]) OrGroup((Gt(age 18) IsNull(deleted_at))) ` +
+		`OrderBy(age desc) Limit(10)`
+	assert.Equal(t, want, q.String())
+}
@@ -1,42 +1,56 @@
 package gormx
 
 import (
-	"strings"
-
-	"github.com/bytedance/sonic"
 	"gorm.io/gorm"
 )
 
 type Kind string
 
 const (
-	kindSelect      Kind = "Select"
-	kindEqual       Kind = "Equal"
-	kindNot         Kind = "Not"
-	kindIn          Kind = "In"
-	kindNotIn       Kind = "NotIn"
-	kindGt          Kind = "Gt"
-	kindGte         Kind = "Gte"
-	kindLt          Kind = "Lt"
-	kindLte         Kind = "Lte"
-	kindLike        Kind = "Like"
-	kindBetween     Kind = "Between"
-	kindOr          Kind = "Or"
-	kindIsNull      Kind = "IsNull"
-	kindNotNull     Kind = "NotNull"
-	kindOrderBy     Kind = "OrderBy"
-	kindCustomOrder Kind = "CustomOrder"
-	kindLimit       Kind = "Limit"
-	kindOffset      Kind = "Offset"
-	kindGroup       Kind = "Group"
+	kindSelect        Kind = "Select"
+	kindDistinct      Kind = "Distinct"
+	kindJoins         Kind = "Joins"
+	kindPreload       Kind = "Preload"
+	kindEqual         Kind = "Equal"
+	kindNot           Kind = "Not"
+	kindIn            Kind = "In"
+	kindNotIn         Kind = "NotIn"
+	kindInSubQuery    Kind = "InSubQuery"
+	kindNotInSubQuery Kind = "NotInSubQuery"
+	kindGt            Kind = "Gt"
+	kindGte           Kind = "Gte"
+	kindLt            Kind = "Lt"
+	kindLte           Kind = "Lte"
+	kindLike          Kind = "Like"
+	kindBetween       Kind = "Between"
+	kindOr            Kind = "Or"
+	kindAndGroup      Kind = "AndGroup"
+	kindOrGroup       Kind = "OrGroup"
+	kindCursor        Kind = "Cursor"
+	kindIsNull        Kind = "IsNull"
+	kindNotNull       Kind = "NotNull"
+	kindOrderBy       Kind = "OrderBy"
+	kindCustomOrder   Kind = "CustomOrder"
+	kindLimit         Kind = "Limit"
+	kindOffset        Kind = "Offset"
+	kindGroup         Kind = "Group"
+	kindHaving        Kind = "Having"
+	kindScopes        Kind = "Scopes"
+	kindDeleted       Kind = "Deleted"
 )
 
 var execOrder = []Kind{
 	kindSelect,
+	kindDistinct,
+	kindJoins,
+	kindPreload,
+	kindDeleted,
 	kindEqual,
 	kindNot,
 	kindIn,
 	kindNotIn,
+	kindInSubQuery,
+	kindNotInSubQuery,
 	kindGt,
 	kindGte,
 	kindLt,
@@ -45,16 +59,28 @@ var execOrder = []Kind{
 	kindBetween,
 	kindIsNull,
 	kindNotNull,
+	kindCursor,
+	kindAndGroup,
 	kindOr,
+	kindOrGroup,
+	kindScopes,
 	kindGroup,
+	kindHaving,
 	kindOrderBy,
 	kindCustomOrder,
 	kindLimit,
 	kindOffset,
 }
 
+// Query 查询条件构建器
+//
+// 构建完成后的 Query 可以被多个 goroutine 并发调用 WithDB、Scope 等只读方法；
+// 需要在共享的 Query 上追加条件时，先通过 Clone 拷贝，避免条件在不同的分支间泄漏。
 type Query struct {
 	conMap map[Kind]Condition
+
+	model   any                 // 绑定的模型，用于校验列并作为 gorm 的 Model
+	allowed map[string]struct{} // 允许引用的列
 }
 
 func NewQuery() *Query {
@@ -69,7 +95,16 @@ type Page interface {
 	GetPageSize() uint64
 }
 
+// WithDB 将条件应用到 db 上，校验模式下引用了不允许的列时 db 会携带 ErrUnknownColumn 错误
 func (q *Query) WithDB(db *gorm.DB) *gorm.DB {
+	if q.model != nil && db.Statement.Model == nil {
+		db = db.Model(q.model)
+	}
+	if err := q.validate(db); err != nil {
+		_ = db.AddError(err)
+		return db
+	}
+
 	for _, k := range execOrder {
 		cond, ok := q.conMap[k]
 		if ok {
@@ -90,6 +125,18 @@ func (q *Query) Eq(key string, value any) *Query {
 	return q
 }
 
+// EqWithFunction 与 Eq 相同，但比较的是 fc 作用于列上的结果，params 为函数的其他参数
+func (q *Query) EqWithFunction(key string, value any, fc Function, params ...string) *Query {
+	cond, ok := q.conMap[kindEqual]
+	if ok {
+		cond.Build(key, funcArgs(fc, params, value)...)
+		return q
+	}
+
+	q.conMap[kindEqual] = newEqualCond().Build(key, funcArgs(fc, params, value)...)
+	return q
+}
+
 func (q *Query) Not(key string, value any) *Query {
 	cond, ok := q.conMap[kindNot]
 	if ok {
@@ -101,6 +148,18 @@ func (q *Query) Not(key string, value any) *Query {
 	return q
 }
 
+// NotWithFunction 与 Not 相同，但比较的是 fc 作用于列上的结果，params 为函数的其他参数
+func (q *Query) NotWithFunction(key string, value any, fc Function, params ...string) *Query {
+	cond, ok := q.conMap[kindNot]
+	if ok {
+		cond.Build(key, funcArgs(fc, params, value)...)
+		return q
+	}
+
+	q.conMap[kindNot] = newNotCond().Build(key, funcArgs(fc, params, value)...)
+	return q
+}
+
 func (q *Query) In(key string, value any) *Query {
 	cond, ok := q.conMap[kindIn]
 	if ok {
@@ -112,6 +171,18 @@ func (q *Query) In(key string, value any) *Query {
 	return q
 }
 
+// InWithFunction 与 In 相同，但比较的是 fc 作用于列上的结果，params 为函数的其他参数
+func (q *Query) InWithFunction(key string, value any, fc Function, params ...string) *Query {
+	cond, ok := q.conMap[kindIn]
+	if ok {
+		cond.Build(key, funcArgs(fc, params, value)...)
+		return q
+	}
+
+	q.conMap[kindIn] = newInCond().Build(key, funcArgs(fc, params, value)...)
+	return q
+}
+
 func (q *Query) NotIn(key string, value any) *Query {
 	cond, ok := q.conMap[kindNotIn]
 	if ok {
@@ -123,6 +194,18 @@ func (q *Query) NotIn(key string, value any) *Query {
 	return q
 }
 
+// NotInWithFunction 与 NotIn 相同，但比较的是 fc 作用于列上的结果，params 为函数的其他参数
+func (q *Query) NotInWithFunction(key string, value any, fc Function, params ...string) *Query {
+	cond, ok := q.conMap[kindNotIn]
+	if ok {
+		cond.Build(key, funcArgs(fc, params, value)...)
+		return q
+	}
+
+	q.conMap[kindNotIn] = newNotInCond().Build(key, funcArgs(fc, params, value)...)
+	return q
+}
+
 func (q *Query) Gt(key string, value any) *Query {
 	cond, ok := q.conMap[kindGt]
 	if ok {
@@ -134,6 +217,18 @@ func (q *Query) Gt(key string, value any) *Query {
 	return q
 }
 
+// GtWithFunction 与 Gt 相同，但比较的是 fc 作用于列上的结果，params 为函数的其他参数
+func (q *Query) GtWithFunction(key string, value any, fc Function, params ...string) *Query {
+	cond, ok := q.conMap[kindGt]
+	if ok {
+		cond.Build(key, funcArgs(fc, params, value)...)
+		return q
+	}
+
+	q.conMap[kindGt] = newGtCond().Build(key, funcArgs(fc, params, value)...)
+	return q
+}
+
 func (q *Query) Gte(key string, value any) *Query {
 	cond, ok := q.conMap[kindGte]
 	if ok {
@@ -145,6 +240,18 @@ func (q *Query) Gte(key string, value any) *Query {
 	return q
 }
 
+// GteWithFunction 与 Gte 相同，但比较的是 fc 作用于列上的结果，params 为函数的其他参数
+func (q *Query) GteWithFunction(key string, value any, fc Function, params ...string) *Query {
+	cond, ok := q.conMap[kindGte]
+	if ok {
+		cond.Build(key, funcArgs(fc, params, value)...)
+		return q
+	}
+
+	q.conMap[kindGte] = newGteCond().Build(key, funcArgs(fc, params, value)...)
+	return q
+}
+
 func (q *Query) Lt(key string, value any) *Query {
 	cond, ok := q.conMap[kindLt]
 	if ok {
@@ -156,6 +263,18 @@ func (q *Query) Lt(key string, value any) *Query {
 	return q
 }
 
+// LtWithFunction 与 Lt 相同，但比较的是 fc 作用于列上的结果，params 为函数的其他参数
+func (q *Query) LtWithFunction(key string, value any, fc Function, params ...string) *Query {
+	cond, ok := q.conMap[kindLt]
+	if ok {
+		cond.Build(key, funcArgs(fc, params, value)...)
+		return q
+	}
+
+	q.conMap[kindLt] = newLtCond().Build(key, funcArgs(fc, params, value)...)
+	return q
+}
+
 func (q *Query) Lte(key string, value any) *Query {
 	cond, ok := q.conMap[kindLte]
 	if ok {
@@ -167,6 +286,18 @@ func (q *Query) Lte(key string, value any) *Query {
 	return q
 }
 
+// LteWithFunction 与 Lte 相同，但比较的是 fc 作用于列上的结果，params 为函数的其他参数
+func (q *Query) LteWithFunction(key string, value any, fc Function, params ...string) *Query {
+	cond, ok := q.conMap[kindLte]
+	if ok {
+		cond.Build(key, funcArgs(fc, params, value)...)
+		return q
+	}
+
+	q.conMap[kindLte] = newLteCond().Build(key, funcArgs(fc, params, value)...)
+	return q
+}
+
 func (q *Query) Like(key string, value any) *Query {
 	cond, ok := q.conMap[kindLike]
 	if ok {
@@ -178,14 +309,50 @@ func (q *Query) Like(key string, value any) *Query {
 	return q
 }
 
-func (q *Query) LikeWithFunction(key string, value any, fc Function) *Query {
+func (q *Query) LikeWithFunction(key string, value any, fc Function, params ...string) *Query {
 	cond, ok := q.conMap[kindLike]
 	if ok {
-		cond.Build(key, value, fc)
+		cond.Build(key, funcArgs(fc, params, value)...)
 		return q
 	}
 
-	q.conMap[kindLike] = newLikeCond().Build(key, value, fc)
+	q.conMap[kindLike] = newLikeCond().Build(key, funcArgs(fc, params, value)...)
+	return q
+}
+
+// LikePrefix 匹配以 value 开头的值，可以使用列上的索引
+func (q *Query) LikePrefix(key string, value any) *Query {
+	return q.like(likeVal{key: key, val: value, mode: likePrefix})
+}
+
+// LikeSuffix 匹配以 value 结尾的值
+func (q *Query) LikeSuffix(key string, value any) *Query {
+	return q.like(likeVal{key: key, val: value, mode: likeSuffix})
+}
+
+// NotLike 排除包含 value 的值
+func (q *Query) NotLike(key string, value any) *Query {
+	return q.like(likeVal{key: key, val: value, not: true})
+}
+
+// LikePattern 将 pattern 原样作为 LIKE 的模式，调用方需自行处理其中的 % 及 _
+func (q *Query) LikePattern(key string, pattern string) *Query {
+	return q.like(likeVal{key: key, val: pattern, mode: likePattern})
+}
+
+// ILike 不区分大小写地匹配包含 value 的值，PostgreSQL 使用 ILIKE，其他数据库使用 LOWER
+func (q *Query) ILike(key string, value any) *Query {
+	return q.like(likeVal{key: key, val: value, insensitive: true})
+}
+
+func (q *Query) like(lv likeVal) *Query {
+	cond, ok := q.conMap[kindLike]
+	if ok {
+		cond.Build(lv.key, lv)
+		return q
+	}
+
+	q.conMap[kindLike] = newLikeCond().Build(lv.key, lv)
 	return q
 }
 
@@ -200,6 +367,18 @@ func (q *Query) Between(key string, lower, upper any) *Query {
 	return q
 }
 
+// BetweenWithFunction 与 Between 相同，但比较的是 fc 作用于列上的结果，params 为函数的其他参数
+func (q *Query) BetweenWithFunction(key string, lower, upper any, fc Function, params ...string) *Query {
+	cond, ok := q.conMap[kindBetween]
+	if ok {
+		cond.Build(key, funcArgs(fc, params, lower, upper)...)
+		return q
+	}
+
+	q.conMap[kindBetween] = newBetweenCond().Build(key, funcArgs(fc, params, lower, upper)...)
+	return q
+}
+
 func (q *Query) Or(key string, value any) *Query {
 	cond, ok := q.conMap[kindOr]
 	if ok {
@@ -211,6 +390,34 @@ func (q *Query) Or(key string, value any) *Query {
 	return q
 }
 
+// AndGroup 添加一组用括号包裹的 AND 条件，组内的条件通过 fn 构建
+//
+// 例如 q.AndGroup(func(sub *Query) { sub.Eq("a", 1).Or("b = ?", 2) }) 生成 AND (a = 1 OR b = 2)
+func (q *Query) AndGroup(fn func(sub *Query)) *Query {
+	cond, ok := q.conMap[kindAndGroup]
+	if ok {
+		cond.Build("", fn)
+		return q
+	}
+
+	q.conMap[kindAndGroup] = newNestedCond(false).Build("", fn)
+	return q
+}
+
+// OrGroup 添加一组用括号包裹的 OR 条件，组内的条件通过 fn 构建
+//
+// 例如 q.Eq("a", 1).OrGroup(func(sub *Query) { sub.Eq("b", 2).Eq("c", 3) }) 生成 a = 1 OR (b = 2 AND c = 3)
+func (q *Query) OrGroup(fn func(sub *Query)) *Query {
+	cond, ok := q.conMap[kindOrGroup]
+	if ok {
+		cond.Build("", fn)
+		return q
+	}
+
+	q.conMap[kindOrGroup] = newNestedCond(true).Build("", fn)
+	return q
+}
+
 func (q *Query) IsNull(key string) *Query {
 	cond, ok := q.conMap[kindIsNull]
 	if ok {
@@ -249,20 +456,18 @@ func (q *Query) Page(page Page) *Query {
 	}
 
 	if orderBy != "" {
-		orderByMap := make(map[string]string)
-		if err := sonic.UnmarshalString(orderBy, &orderByMap); err != nil {
-			return q
-		}
-
-		for flied, order := range orderByMap { // descend|ascend => desc|asc
-			q = q.OrderBy(flied + " " + strings.TrimSuffix(order, "end"))
-			break // current only support one.
+		fields, err := ParseSort(orderBy)
+		if err != nil {
+			// 不参与排序，仅在校验模式下报错
+			return q.addOrder(orderItem{invalid: orderBy})
 		}
+		q = q.Sort(fields...)
 	}
 
 	return q
 }
 
+// OrderBy 添加排序，支持 ParseSort 的各种格式，无法解析的表达式原样传给 gorm
 func (q *Query) OrderBy(key string) *Query {
 	cond, ok := q.conMap[kindOrderBy]
 	if ok {
@@ -274,6 +479,32 @@ func (q *Query) OrderBy(key string) *Query {
 	return q
 }
 
+// WithDeleted 同时查询软删除的记录，该模式下 DeleteByQuery 为物理删除
+func (q *Query) WithDeleted() *Query {
+	return q.deleted(deletedInclude)
+}
+
+// OnlyDeleted 只查询软删除的记录，该模式下 DeleteByQuery 为物理删除，可用于清理已软删除的记录
+func (q *Query) OnlyDeleted() *Query {
+	return q.deleted(deletedOnly)
+}
+
+// WithoutDeleted 显式排除软删除的记录，传入的 db 已调用 Unscoped 时同样生效，可用于覆盖 WithDeleted、OnlyDeleted
+func (q *Query) WithoutDeleted() *Query {
+	return q.deleted(deletedExclude)
+}
+
+func (q *Query) deleted(mode deletedMode) *Query {
+	cond, ok := q.conMap[kindDeleted]
+	if ok {
+		cond.Build("", mode)
+		return q
+	}
+
+	q.conMap[kindDeleted] = newDeletedCond().Build("", mode)
+	return q
+}
+
 func (q *Query) Limit(limit int) *Query {
 	cond, ok := q.conMap[kindLimit]
 	if ok {
@@ -328,3 +559,87 @@ func (q *Query) Group(fields ...any) *Query {
 	q.conMap[kindGroup] = newGroupCond().Build("", fields...)
 	return q
 }
+
+// Distinct 去重查询，fields 为空时对 Select 的字段去重
+func (q *Query) Distinct(fields ...any) *Query {
+	cond, ok := q.conMap[kindDistinct]
+	if ok {
+		cond.Build("", fields...)
+		return q
+	}
+
+	q.conMap[kindDistinct] = newDistinctCond().Build("", fields...)
+	return q
+}
+
+// Joins 添加 LEFT JOIN，query 可以是关联名（如 "Company"）或 JOIN 子句，args 原样传给 gorm
+func (q *Query) Joins(query string, args ...any) *Query {
+	cond, ok := q.conMap[kindJoins]
+	if ok {
+		cond.Build(query, args...)
+		return q
+	}
+
+	q.conMap[kindJoins] = newJoinsCond().Build(query, args...)
+	return q
+}
+
+// JoinsWith 关联 association 并在 ON 中添加 fn 构建的条件，条件中的列需带上关联名，如 "Company.name"
+func (q *Query) JoinsWith(association string, fn func(sub *Query)) *Query {
+	return q.Joins(association, fn)
+}
+
+// Preload 预加载关联，args 原样传给 gorm
+func (q *Query) Preload(association string, args ...any) *Query {
+	cond, ok := q.conMap[kindPreload]
+	if ok {
+		cond.Build(association, args...)
+		return q
+	}
+
+	q.conMap[kindPreload] = newPreloadCond().Build(association, args...)
+	return q
+}
+
+// PreloadWith 预加载关联，并使用 fn 构建的条件、排序及分页过滤关联的记录
+func (q *Query) PreloadWith(association string, fn func(sub *Query)) *Query {
+	return q.Preload(association, fn)
+}
+
+// InSubQuery 生成 key IN (SELECT ...)，sub 需通过 Model 绑定模型，并通过 Select 指定查询的列
+func (q *Query) InSubQuery(key string, sub *Query) *Query {
+	cond, ok := q.conMap[kindInSubQuery]
+	if ok {
+		cond.Build(key, sub)
+		return q
+	}
+
+	q.conMap[kindInSubQuery] = newSubQueryCond(false).Build(key, sub)
+	return q
+}
+
+// NotInSubQuery 生成 key NOT IN (SELECT ...)，sub 的要求同 InSubQuery
+func (q *Query) NotInSubQuery(key string, sub *Query) *Query {
+	cond, ok := q.conMap[kindNotInSubQuery]
+	if ok {
+		cond.Build(key, sub)
+		return q
+	}
+
+	q.conMap[kindNotInSubQuery] = newSubQueryCond(true).Build(key, sub)
+	return q
+}
+
+// Having 添加 HAVING 条件，条件通过 fn 使用比较方法构建，聚合可以使用 AggCount、AggSum 等函数
+//
+// 例如 q.Group("name").Having(func(sub *Query) { sub.GtWithFunction("age", 18, AggSum) }) 生成 HAVING SUM(age) > 18
+func (q *Query) Having(fn func(sub *Query)) *Query {
+	cond, ok := q.conMap[kindHaving]
+	if ok {
+		cond.Build("", fn)
+		return q
+	}
+
+	q.conMap[kindHaving] = newHavingCond().Build("", fn)
+	return q
+}
@@ -1,11 +1,75 @@
 package gormx
 
 import (
+	"context"
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils/tests"
 )
 
+type user struct {
+	ID   int64
+	Name string
+	Age  int
+}
+
+func newDryRunDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func newPostgresDryRunDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func newSQLiteDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库的每个连接相互独立，限制为单连接
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := db.AutoMigrate(&user{}); err != nil {
+		t.Fatal(err)
+	}
+	users := []user{
+		{Name: "Alice", Age: 18},
+		{Name: "bob", Age: 25},
+		{Name: "Carol", Age: 32},
+	}
+	if err := db.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func toSQL(db *gorm.DB, q *Query) string {
+	return db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		return q.WithDB(tx).Find(&[]user{})
+	})
+}
+
 type pageData struct {
 	Page     uint64
 	PageSize uint64
@@ -67,3 +131,231 @@ func TestQuery_Page(t *testing.T) {
 		})
 	}
 }
+
+func TestQuery_SameColumn(t *testing.T) {
+	db := newDryRunDB(t)
+
+	q := NewQuery().
+		Gt("age", 18).
+		Eq("name", "a").
+		Lt("age", 60).
+		Gt("age", 30).
+		Eq("name", "b").
+		IsNull("deleted_at").
+		IsNull("updated_at")
+	want := "SELECT * FROM `users` WHERE `name` = \"a\" AND `name` = \"b\" AND `age` > 18 AND `age` > 30 AND `age` < 60 " +
+		"AND `deleted_at` IS NULL AND `updated_at` IS NULL"
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, want, toSQL(db, q))
+	}
+}
+
+func TestQuery_Group(t *testing.T) {
+	db := newDryRunDB(t)
+
+	tests := []struct {
+		name string
+		q    *Query
+		want string
+	}{
+		{
+			name: "and group or group",
+			q: NewQuery().
+				AndGroup(func(sub *Query) { sub.Eq("a", 1).Eq("b", 2) }).
+				OrGroup(func(sub *Query) { sub.Eq("c", 3) }),
+			want: "SELECT * FROM `users` WHERE (`a` = 1 AND `b` = 2) OR `c` = 3",
+		},
+		{
+			name: "or inside and group",
+			q: NewQuery().
+				Eq("a", 1).
+				AndGroup(func(sub *Query) { sub.Eq("b", 2).OrGroup(func(sub *Query) { sub.Eq("c", 3) }) }),
+			want: "SELECT * FROM `users` WHERE `a` = 1 AND (`b` = 2 OR `c` = 3)",
+		},
+		{
+			name: "multi or group",
+			q: NewQuery().
+				OrGroup(func(sub *Query) { sub.Eq("a", 1).Gt("b", 2) }).
+				OrGroup(func(sub *Query) { sub.Eq("c", 3).Lt("d", 4) }),
+			want: "SELECT * FROM `users` WHERE (`a` = 1 AND `b` > 2) OR (`c` = 3 AND `d` < 4)",
+		},
+		{
+			name: "empty group",
+			q:    NewQuery().Eq("a", 1).AndGroup(func(sub *Query) {}),
+			want: "SELECT * FROM `users` WHERE `a` = 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, toSQL(db, tt.q))
+		})
+	}
+}
+
+func TestQuery_Dialect(t *testing.T) {
+	q := NewQuery().
+		Eq("name", "bob").
+		In("age", []int{18, 25}).
+		Between("age", 10, 30).
+		NotNull("name").
+		LikeWithFunction("name", "B", Lower)
+
+	t.Run("postgres", func(t *testing.T) {
+		db := newPostgresDryRunDB(t)
+		want := `SELECT * FROM "users" WHERE "name" = 'bob' AND "age" IN (18,25) AND LOWER("name") LIKE '%b%' ` +
+			`AND ("age" BETWEEN 10 AND 30) AND "name" IS NOT NULL`
+		assert.Equal(t, want, toSQL(db, q))
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		db := newSQLiteDB(t)
+		want := "SELECT * FROM `users` WHERE `name` = \"bob\" AND `age` IN (18,25) AND LOWER(`name`) LIKE \"%b%\" ESCAPE '\\' " +
+			"AND (`age` BETWEEN 10 AND 30) AND `name` IS NOT NULL"
+		assert.Equal(t, want, toSQL(db, q))
+
+		var got []user
+		err := q.WithDB(db).Find(&got).Error
+		assert.NoError(t, err)
+		if assert.Len(t, got, 1) {
+			assert.Equal(t, "bob", got[0].Name)
+		}
+	})
+}
+
+func TestQuery_LikeVariants(t *testing.T) {
+	tests := []struct {
+		name   string
+		q      *Query
+		want   string
+		wantPG string
+	}{
+		{
+			name:   "escape",
+			q:      NewQuery().Like("name", `50%_off\`),
+			want:   "SELECT * FROM `users` WHERE `name` LIKE \"%50\\%\\_off\\\\%\"",
+			wantPG: `SELECT * FROM "users" WHERE "name" LIKE '%50\%\_off\\%'`,
+		},
+		{
+			name:   "prefix and suffix",
+			q:      NewQuery().LikePrefix("name", "bo").LikeSuffix("name", "b"),
+			want:   "SELECT * FROM `users` WHERE `name` LIKE \"bo%\" AND `name` LIKE \"%b\"",
+			wantPG: `SELECT * FROM "users" WHERE "name" LIKE 'bo%' AND "name" LIKE '%b'`,
+		},
+		{
+			name:   "not like",
+			q:      NewQuery().NotLike("name", "o"),
+			want:   "SELECT * FROM `users` WHERE `name` NOT LIKE \"%o%\"",
+			wantPG: `SELECT * FROM "users" WHERE "name" NOT LIKE '%o%'`,
+		},
+		{
+			name:   "pattern",
+			q:      NewQuery().LikePattern("name", "b_b%"),
+			want:   "SELECT * FROM `users` WHERE `name` LIKE \"b_b%\"",
+			wantPG: `SELECT * FROM "users" WHERE "name" LIKE 'b_b%'`,
+		},
+		{
+			name:   "insensitive",
+			q:      NewQuery().ILike("name", "BO"),
+			want:   "SELECT * FROM `users` WHERE LOWER(`name`) LIKE \"%bo%\"",
+			wantPG: `SELECT * FROM "users" WHERE "name" ILIKE '%BO%'`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, toSQL(newDryRunDB(t), tt.q))
+			assert.Equal(t, tt.wantPG, toSQL(newPostgresDryRunDB(t), tt.q))
+		})
+	}
+}
+
+func TestQuery_LikeSQLite(t *testing.T) {
+	db := newSQLiteDB(t)
+	if err := db.Create(&user{Name: "50% off", Age: 50}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	find := func(q *Query) []int {
+		var got []user
+		assert.NoError(t, q.OrderBy("age").WithDB(db).Find(&got).Error)
+		return ages(got)
+	}
+	assert.Equal(t, []int{50}, find(NewQuery().Like("name", "50%")))
+	assert.Equal(t, []int{50}, find(NewQuery().LikePrefix("name", "50%")))
+	assert.Equal(t, []int{25}, find(NewQuery().LikeSuffix("name", "ob")))
+	assert.Equal(t, []int{18, 25, 32}, find(NewQuery().NotLike("name", "%")))
+	assert.Equal(t, []int{25}, find(NewQuery().LikePattern("name", "b_b")))
+	assert.Equal(t, []int{18, 32}, find(NewQuery().ILike("name", "L")))
+}
+
+type post struct {
+	ID        int64
+	Title     string
+	DeletedAt gorm.DeletedAt
+}
+
+func TestQuery_SoftDelete(t *testing.T) {
+	db := newDryRunDB(t)
+	postSQL := func(db *gorm.DB, q *Query) string {
+		return db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+			return q.WithDB(tx).Find(&[]post{})
+		})
+	}
+
+	assert.Equal(t, "SELECT * FROM `posts` WHERE `id` = 1 AND `posts`.`deleted_at` IS NULL",
+		postSQL(db, NewQuery().Eq("id", 1)))
+	assert.Equal(t, "SELECT * FROM `posts` WHERE `id` = 1",
+		postSQL(db, NewQuery().WithDeleted().Eq("id", 1)))
+	assert.Equal(t, "SELECT * FROM `posts` WHERE `posts`.`deleted_at` IS NOT NULL AND `id` = 1",
+		postSQL(db, NewQuery().OnlyDeleted().Eq("id", 1)))
+	assert.Equal(t, "SELECT * FROM `posts` WHERE `id` = 1 AND `posts`.`deleted_at` IS NULL",
+		postSQL(db, NewQuery().OnlyDeleted().WithoutDeleted().Eq("id", 1)))
+	assert.Equal(t, "SELECT * FROM `posts` WHERE `posts`.`deleted_at` IS NULL AND `id` = 1",
+		postSQL(db.Unscoped(), NewQuery().WithoutDeleted().Eq("id", 1)))
+
+	merged := NewQuery().WithDeleted().Merge(NewQuery().OnlyDeleted())
+	assert.Equal(t, "Deleted(only)", merged.String())
+
+	b, err := merged.MarshalJSON()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"version":1,"conditions":[{"kind":"Deleted","op":"only"}]}`, string(b))
+	decoded := NewQuery()
+	assert.NoError(t, decoded.UnmarshalJSON(b))
+	assert.Equal(t, postSQL(db, merged), postSQL(db, decoded))
+	assert.True(t, errors.Is(decoded.UnmarshalJSON([]byte(`{"version":1,"conditions":[{"kind":"Deleted","op":"all"}]}`)), ErrInvalidQuery))
+
+	tx := NewQuery().OnlyDeleted().WithDB(db.Model(&user{})).Find(&[]user{})
+	assert.True(t, errors.Is(tx.Error, ErrNotSoftDelete), tx.Error)
+}
+
+func TestQuery_SoftDeleteSQLite(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, db.AutoMigrate(&post{}))
+	assert.NoError(t, db.Create([]post{{ID: 1, Title: "a"}, {ID: 2, Title: "b"}, {ID: 3, Title: "c"}}).Error)
+	assert.NoError(t, db.Delete(&post{}, 2).Error)
+
+	ctx := context.Background()
+	ids := func(q *Query) []int64 {
+		posts, err := Find[post](ctx, db, q.OrderBy("id"))
+		assert.NoError(t, err)
+		res := make([]int64, 0, len(posts))
+		for _, p := range posts {
+			res = append(res, p.ID)
+		}
+		return res
+	}
+	assert.Equal(t, []int64{1, 3}, ids(NewQuery()))
+	assert.Equal(t, []int64{1, 2, 3}, ids(NewQuery().WithDeleted()))
+	assert.Equal(t, []int64{2}, ids(NewQuery().OnlyDeleted()))
+
+	total, err := Count[post](ctx, db, NewQuery().OnlyDeleted())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+
+	n, err := DeleteByQuery(ctx, db, NewQuery().Model(&post{}).OnlyDeleted().Gt("id", 0))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.Equal(t, []int64{1, 3}, ids(NewQuery().WithDeleted()))
+}
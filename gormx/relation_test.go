@@ -0,0 +1,172 @@
+package gormx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type company struct {
+	ID    int64
+	Name  string
+	Alive bool
+}
+
+type employee struct {
+	ID        int64
+	Name      string
+	CompanyID int64
+	Company   *company
+	Orders    []order
+}
+
+type order struct {
+	ID         int64
+	EmployeeID int64
+	Amount     int
+}
+
+func newRelationDB(t *testing.T) *gorm.DB {
+	db := newSQLiteDB(t)
+	if err := db.AutoMigrate(&company{}, &employee{}, &order{}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := []any{
+		&[]company{{ID: 1, Name: "acme", Alive: true}, {ID: 2, Name: "gone"}},
+		&[]employee{{ID: 1, Name: "bob", CompanyID: 1}, {ID: 2, Name: "alice", CompanyID: 2}, {ID: 3, Name: "carol", CompanyID: 1}},
+		&[]order{{EmployeeID: 1, Amount: 10}, {EmployeeID: 1, Amount: 30}, {EmployeeID: 3, Amount: 5}},
+	}
+	for _, r := range rows {
+		if err := db.Create(r).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestQuery_RelationSQL(t *testing.T) {
+	db := newDryRunDB(t)
+
+	tests := []struct {
+		name string
+		q    *Query
+		want string
+	}{
+		{
+			name: "distinct",
+			q:    NewQuery().Distinct("name", "age"),
+			want: "SELECT DISTINCT `name`,`age` FROM `users`",
+		},
+		{
+			name: "having",
+			q: NewQuery().Select("name").Group("name").Having(func(sub *Query) {
+				sub.GteWithFunction("*", 2, AggCount).LtWithFunction("age", 100, AggMax)
+			}),
+			want: "SELECT `name` FROM `users` GROUP BY `name` HAVING COUNT(*) >= 2 AND MAX(`age`) < 100",
+		},
+		{
+			name: "in subquery",
+			q: NewQuery().Eq("name", "bob").
+				InSubQuery("id", NewQuery().Model(&order{}).Select("employee_id").Gt("amount", 10)).
+				NotInSubQuery("id", NewQuery().Model(&order{}).Select("employee_id").Lt("amount", 0)),
+			want: "SELECT * FROM `users` WHERE `name` = \"bob\" " +
+				"AND `id` IN (SELECT `employee_id` FROM `orders` WHERE `amount` > 10) " +
+				"AND `id` NOT IN (SELECT `employee_id` FROM `orders` WHERE `amount` < 0)",
+		},
+		{
+			name: "raw joins",
+			q:    NewQuery().Joins("JOIN orders ON orders.employee_id = users.id AND orders.amount > ?", 5).Gt("orders.amount", 1),
+			want: "SELECT `users`.`id`,`users`.`name`,`users`.`age` FROM `users` " +
+				"JOIN orders ON orders.employee_id = users.id AND orders.amount > 5 WHERE `orders`.`amount` > 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, toSQL(db, tt.q))
+		})
+	}
+
+	err := NewQuery().InSubQuery("id", NewQuery().Select("id")).WithDB(db).Find(&[]user{}).Error
+	assert.True(t, errors.Is(err, ErrSubQueryModel), err)
+}
+
+func TestQuery_Relations(t *testing.T) {
+	db := newRelationDB(t)
+	ctx := context.Background()
+
+	got, err := Find[employee](ctx, db, NewQuery().
+		JoinsWith("Company", func(sub *Query) { sub.Eq("Company.alive", true) }).
+		NotNull("Company.id").
+		PreloadWith("Orders", func(sub *Query) { sub.Gte("amount", 10).OrderBy("amount desc") }).
+		OrderBy("employees.id"))
+	assert.NoError(t, err)
+	if assert.Len(t, got, 2) {
+		assert.Equal(t, "bob", got[0].Name)
+		assert.Equal(t, "acme", got[0].Company.Name)
+		assert.Equal(t, []order{{ID: 2, EmployeeID: 1, Amount: 30}, {ID: 1, EmployeeID: 1, Amount: 10}}, got[0].Orders)
+		assert.Equal(t, "carol", got[1].Name)
+		assert.Empty(t, got[1].Orders)
+	}
+
+	type total struct {
+		EmployeeID int64
+		Sum        int
+	}
+	_, err = Find[total](ctx, db, NewQuery().Model(&order{}).
+		Select("employee_id", "SUM(amount) AS sum").
+		Group("employee_id").
+		Having(func(sub *Query) { sub.GtWithFunction("amount", 10, AggSum) }))
+	assert.True(t, errors.Is(err, ErrUnknownColumn), err)
+
+	totals, err := Find[total](ctx, db, NewQuery().Model(&order{}).
+		Select("employee_id", "SUM(amount) AS sum").
+		Group("employee_id").
+		Having(func(sub *Query) { sub.GtWithFunction("amount", 10, AggSum) }).
+		AllowColumns("SUM(amount) AS sum"))
+	assert.NoError(t, err)
+	assert.Equal(t, []total{{EmployeeID: 1, Sum: 40}}, totals)
+
+	ids, err := Find[user](ctx, db, NewQuery().Model(&user{}).
+		InSubQuery("id", NewQuery().Model(&order{}).Distinct("employee_id")).OrderBy("id"))
+	assert.NoError(t, err)
+	assert.Equal(t, []int{18, 32}, ages(ids))
+}
+
+func TestQuery_RelationsStrict(t *testing.T) {
+	db := newRelationDB(t)
+	ctx := context.Background()
+
+	got, err := Find[employee](ctx, db, NewQuery().Model(&employee{}).
+		JoinsWith("Company", func(sub *Query) { sub.Eq("Company.alive", true) }).
+		NotNull("Company.id").
+		PreloadWith("Orders", func(sub *Query) { sub.Gte("amount", 10).OrderBy("amount desc") }).
+		OrderBy("employees.id"))
+	assert.NoError(t, err)
+	assert.Len(t, got, 2)
+
+	tests := map[string]*Query{
+		"raw join":            NewQuery().Joins("JOIN orders ON orders.employee_id = employees.id"),
+		"unknown association": NewQuery().Joins("Manager"),
+		"unknown join column": NewQuery().JoinsWith("Company", func(sub *Query) { sub.Eq("Company.secret", 1) }),
+		"raw join condition":  NewQuery().JoinsWith("Company", func(sub *Query) { sub.Or("1 = 1 OR Company.id", 1) }),
+		"raw preload":         NewQuery().Preload("Orders", "amount > 0 OR 1 = 1"),
+		"unknown preload":     NewQuery().PreloadWith("Orders", func(sub *Query) { sub.Gt("secret", 1) }),
+		"column of relation":  NewQuery().Eq("Company", 1),
+	}
+	for name, q := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Find[employee](ctx, db, q.Model(&employee{}))
+			assert.True(t, errors.Is(err, ErrUnknownColumn), err)
+		})
+	}
+
+	// 只设置了允许的列时，关联名同样需要被允许
+	_, err = Find[employee](ctx, db, NewQuery().AllowColumns("name").Joins("Company"))
+	assert.True(t, errors.Is(err, ErrUnknownColumn), err)
+	_, err = Find[employee](ctx, db, NewQuery().AllowColumns("name", "Company").Joins("Company"))
+	assert.NoError(t, err)
+}
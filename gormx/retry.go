@@ -0,0 +1,57 @@
+package gormx
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+var defaultBackoff = backoff{base: 10 * time.Millisecond, max: time.Second}
+
+// backoff 指数退避，实际间隔在 [d/2, d] 之间随机，避免冲突的请求同时重试
+type backoff struct {
+	base time.Duration
+	max  time.Duration
+}
+
+// delay 第 attempt 次重试前的等待时间，attempt 从 1 开始
+func (b backoff) delay(attempt int) time.Duration {
+	d := b.max
+	if attempt < 32 {
+		if v := b.base << (attempt - 1); v > 0 && v < b.max {
+			d = v
+		}
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retry 执行 fn，返回的错误满足 retryable 时等待后重新执行，最多执行 attempts 次，等待期间响应 ctx 的取消
+func retry(ctx context.Context, attempts int, b backoff, retryable func(err error) bool, fn func() error) error {
+	var err error
+	for i := 0; i < max(attempts, 1); i++ {
+		if i > 0 {
+			if serr := sleep(ctx, b.delay(i)); serr != nil {
+				return serr
+			}
+		}
+		if err = fn(); err == nil || !retryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
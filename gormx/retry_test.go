@@ -0,0 +1,21 @@
+package gormx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff_Delay(t *testing.T) {
+	b := backoff{base: 10 * time.Millisecond, max: 50 * time.Millisecond}
+	for i := 0; i < 100; i++ {
+		d := b.delay(1)
+		assert.True(t, d >= 5*time.Millisecond && d <= 10*time.Millisecond, d)
+		d = b.delay(3)
+		assert.True(t, d >= 20*time.Millisecond && d <= 40*time.Millisecond, d)
+		d = b.delay(64)
+		assert.True(t, d >= 25*time.Millisecond && d <= 50*time.Millisecond, d)
+	}
+	assert.Equal(t, time.Duration(0), backoff{}.delay(1))
+}
@@ -0,0 +1,200 @@
+package gormx
+
+import (
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidSort 排序规则无法解析
+var ErrInvalidSort = errors.New("invalid sort")
+
+var columnNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// NullsOrder NULL 值的排序位置
+type NullsOrder int
+
+const (
+	NullsDefault NullsOrder = iota // 使用数据库的默认行为
+	NullsFirst
+	NullsLast
+)
+
+// SortField 单个字段的排序规则
+type SortField struct {
+	Column string
+	Desc   bool
+	Nulls  NullsOrder
+}
+
+// ParseSort 解析多字段排序规则，字段顺序即排序优先级，支持以下格式：
+//
+//	[{"field":"age","order":"desc","nulls":"last"},{"field":"id"}]
+//	{"age":"descend","id":"asc"}     按出现的顺序排序
+//	age:desc:nulls_last,id:asc
+//	age desc nulls last, id
+func ParseSort(spec string) ([]SortField, error) {
+	spec = strings.TrimSpace(spec)
+	switch {
+	case spec == "":
+		return nil, nil
+	case strings.HasPrefix(spec, "["):
+		return parseSortArray(spec)
+	case strings.HasPrefix(spec, "{"):
+		return parseSortObject(spec)
+	default:
+		return parseSortString(spec)
+	}
+}
+
+func parseSortArray(spec string) ([]SortField, error) {
+	var items []struct {
+		Field string `json:"field"`
+		Order string `json:"order"`
+		Nulls string `json:"nulls"`
+	}
+	if err := json.Unmarshal([]byte(spec), &items); err != nil {
+		return nil, errors.Wrap(ErrInvalidSort, err.Error())
+	}
+
+	fields := make([]SortField, 0, len(items))
+	for _, item := range items {
+		tokens := []string{item.Order}
+		if item.Nulls != "" {
+			tokens = append(tokens, "nulls", item.Nulls)
+		}
+		f, err := newSortField(item.Field, tokens)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// parseSortObject 按 key 在 JSON 中出现的顺序解析 {"col": "asc|desc"}
+func parseSortObject(spec string) ([]SortField, error) {
+	dec := json.NewDecoder(strings.NewReader(spec))
+	if _, err := dec.Token(); err != nil {
+		return nil, errors.Wrap(ErrInvalidSort, err.Error())
+	}
+
+	var fields []SortField
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, errors.Wrap(ErrInvalidSort, err.Error())
+		}
+		var order string
+		if err := dec.Decode(&order); err != nil {
+			return nil, errors.Wrap(ErrInvalidSort, err.Error())
+		}
+
+		f, err := newSortField(key.(string), strings.Fields(order))
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	if _, err := dec.Token(); err != nil && err != io.EOF {
+		return nil, errors.Wrap(ErrInvalidSort, err.Error())
+	}
+	return fields, nil
+}
+
+func parseSortString(spec string) ([]SortField, error) {
+	var fields []SortField
+	for _, item := range strings.Split(spec, ",") {
+		tokens := strings.Fields(strings.ReplaceAll(item, ":", " "))
+		if len(tokens) == 0 {
+			return nil, errors.Wrapf(ErrInvalidSort, "empty field in %q", spec)
+		}
+		f, err := newSortField(tokens[0], tokens[1:])
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// newSortField 解析 [asc|desc] [nulls first|last] 形式的修饰
+func newSortField(column string, tokens []string) (SortField, error) {
+	if !columnNameRegexp.MatchString(column) {
+		return SortField{}, errors.Wrapf(ErrInvalidSort, "invalid column %q", column)
+	}
+
+	f := SortField{Column: column}
+	for i := 0; i < len(tokens); i++ {
+		switch t := strings.ToLower(tokens[i]); t {
+		case "", "asc", "ascend":
+		case "desc", "descend":
+			f.Desc = true
+		case "nulls_first", "nullsfirst", "first":
+			f.Nulls = NullsFirst
+		case "nulls_last", "nullslast", "last":
+			f.Nulls = NullsLast
+		case "nulls":
+			if i+1 < len(tokens) {
+				continue
+			}
+			fallthrough
+		default:
+			return SortField{}, errors.Wrapf(ErrInvalidSort, "invalid order %q of %s", t, column)
+		}
+	}
+	return f, nil
+}
+
+// orderByColumn 生成排序子句，按数据库方言处理 NULL 值的位置
+func (f SortField) orderByColumn(db *gorm.DB) clause.OrderByColumn {
+	if f.Nulls == NullsDefault {
+		return clause.OrderByColumn{Column: clause.Column{Name: f.Column}, Desc: f.Desc}
+	}
+
+	column := db.Statement.Quote(f.Column)
+	direction := ""
+	if f.Desc {
+		direction = " DESC"
+	}
+
+	var sql string
+	switch db.Dialector.Name() {
+	case "postgres", "sqlite", "oracle":
+		sql = column + direction + " NULLS FIRST"
+		if f.Nulls == NullsLast {
+			sql = column + direction + " NULLS LAST"
+		}
+	default:
+		// 不支持 NULLS FIRST/LAST 的数据库（如 MySQL、SQL Server）先按是否为 NULL 排序
+		sql = "CASE WHEN " + column + " IS NULL THEN 0 ELSE 1 END," + column + direction
+		if f.Nulls == NullsLast {
+			sql = "CASE WHEN " + column + " IS NULL THEN 1 ELSE 0 END," + column + direction
+		}
+	}
+	return clause.OrderByColumn{Column: clause.Column{Name: sql, Raw: true}}
+}
+
+// Sort 按 fields 的顺序添加排序
+func (q *Query) Sort(fields ...SortField) *Query {
+	for _, f := range fields {
+		q.addOrder(orderItem{field: f})
+	}
+	return q
+}
+
+func (q *Query) addOrder(item orderItem) *Query {
+	cond, ok := q.conMap[kindOrderBy]
+	if ok {
+		cond.Build("", item)
+		return q
+	}
+
+	q.conMap[kindOrderBy] = newOrderByCond().Build("", item)
+	return q
+}
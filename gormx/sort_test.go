@@ -0,0 +1,127 @@
+package gormx
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []SortField
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			spec: " ",
+		},
+		{
+			name: "json array",
+			spec: `[{"field":"age","order":"desc","nulls":"last"},{"field":"id"}]`,
+			want: []SortField{{Column: "age", Desc: true, Nulls: NullsLast}, {Column: "id"}},
+		},
+		{
+			name: "json object keeps order",
+			spec: `{"name":"ascend","age":"descend","id":"asc"}`,
+			want: []SortField{{Column: "name"}, {Column: "age", Desc: true}, {Column: "id"}},
+		},
+		{
+			name: "colon separated",
+			spec: "age:desc:nulls_first,users.id:asc",
+			want: []SortField{{Column: "age", Desc: true, Nulls: NullsFirst}, {Column: "users.id"}},
+		},
+		{
+			name: "sql like",
+			spec: "age DESC NULLS LAST, id",
+			want: []SortField{{Column: "age", Desc: true, Nulls: NullsLast}, {Column: "id"}},
+		},
+		{
+			name:    "invalid column",
+			spec:    `{"sleep(10)":"asc"}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid order",
+			spec:    "age:up",
+			wantErr: true,
+		},
+		{
+			name:    "dangling nulls",
+			spec:    "age desc nulls",
+			wantErr: true,
+		},
+		{
+			name:    "empty field",
+			spec:    "age,,id",
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			spec:    `[{"field":"age"`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSort(tt.spec)
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, ErrInvalidSort), err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestQuery_SortSQL(t *testing.T) {
+	page := &pageData{OrderBy: `[{"field":"age","order":"desc","nulls":"last"},{"field":"id"}]`}
+
+	db := newPostgresDryRunDB(t)
+	assert.Equal(t, `SELECT * FROM "users" ORDER BY "age" DESC NULLS LAST,"id"`, toSQL(db, NewQuery().Page(page)))
+
+	db = newDryRunDB(t)
+	assert.Equal(t,
+		"SELECT * FROM `users` ORDER BY CASE WHEN `age` IS NULL THEN 1 ELSE 0 END,`age` DESC,`id`",
+		toSQL(db, NewQuery().Page(page)))
+
+	q := NewQuery().CustomOrder(`{"name":"asc","age":"desc","id":"asc"}`, "id desc")
+	assert.Equal(t, "SELECT * FROM `users` ORDER BY `name`,`age` DESC,`id`", toSQL(db, q))
+
+	q = NewQuery().CustomOrder(`{"sleep(10)":"asc"}`, "id desc")
+	assert.Equal(t, "SELECT * FROM `users` ORDER BY `id` DESC", toSQL(db, q))
+
+	q = NewQuery().CustomOrder("", "FIELD(id, 3, 1, 2)")
+	assert.Equal(t, "SELECT * FROM `users` ORDER BY FIELD(id, 3, 1, 2)", toSQL(db, q))
+}
+
+func TestQuery_Sort(t *testing.T) {
+	db := newSQLiteDB(t)
+	if err := db.Create(&user{Name: "Alice", Age: 40}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var got []user
+	q := NewQuery().Model(&user{}).Page(&pageData{OrderBy: "name:asc,age:desc"})
+	assert.NoError(t, q.WithDB(db).Find(&got).Error)
+	assert.Equal(t, []int{40, 18, 32, 25}, ages(got))
+
+	got = nil
+	q = NewQuery().Sort(SortField{Column: "age", Desc: true, Nulls: NullsFirst}).Limit(2)
+	assert.NoError(t, q.WithDB(db).Find(&got).Error)
+	assert.Equal(t, []int{40, 32}, ages(got))
+
+	err := NewQuery().AllowColumns("name").Page(&pageData{OrderBy: "name,age:desc"}).WithDB(db).Find(&got).Error
+	assert.True(t, errors.Is(err, ErrUnknownColumn), err)
+}
+
+func ages(users []user) []int {
+	res := make([]int, 0, len(users))
+	for _, u := range users {
+		res = append(res, u.Age)
+	}
+	return res
+}
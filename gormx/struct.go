@@ -0,0 +1,207 @@
+package gormx
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm/schema"
+)
+
+const structTagName = "gormx"
+
+// ErrInvalidFilter 过滤条件结构体不合法，如不是结构体、操作符不支持等
+var ErrInvalidFilter = errors.New("invalid filter")
+
+var pageType = reflect.TypeOf((*Page)(nil)).Elem()
+
+type structOptions struct {
+	keepZero bool
+}
+
+type StructOption func(o *structOptions)
+
+// WithZeroValues 保留零值字段，默认零值及 nil 字段不生成条件
+func WithZeroValues() StructOption {
+	return func(o *structOptions) {
+		o.keepZero = true
+	}
+}
+
+// FromStruct 根据结构体标签构建 Query，标签格式为 `gormx:"column:name;op:like"`
+//
+// column 默认为字段名的蛇形命名；op 默认为 eq，支持 eq、not、in、not_in、gt、gte、lt、lte、like、between；
+// between 要求字段为长度为 2 的切片或数组，只有一端有值时退化为 gte 或 lte。
+// 标签为 "-" 的字段被忽略；嵌入的结构体实现了 Page 时调用 Query.Page，否则展开其字段。
+// 默认忽略零值及 nil 字段，非 nil 的指针字段即使指向零值也会生成条件。
+func FromStruct(filter any, opts ...StructOption) (*Query, error) {
+	o := &structOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	v := reflect.ValueOf(filter)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, errors.Wrap(ErrInvalidFilter, "filter is nil")
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, errors.Wrapf(ErrInvalidFilter, "filter must be struct, got %s", v.Kind())
+	}
+
+	q := NewQuery()
+	if err := buildFromStruct(q, v, o); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func buildFromStruct(q *Query, v reflect.Value, o *structOptions) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get(structTagName)
+		if tag == "-" {
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		fv := v.Field(i)
+		if field.Anonymous && tag == "" {
+			if page, ok := asPage(fv); ok {
+				q.Page(page)
+				continue
+			}
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				if err := buildFromStruct(q, fv, o); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		settings := schema.ParseTagSetting(tag, ";")
+		column := settings["COLUMN"]
+		if column == "" {
+			column = schema.NamingStrategy{}.ColumnName("", field.Name)
+		}
+		op := strings.ToLower(settings["OP"])
+		if op == "" {
+			op = "eq"
+		}
+
+		if err := applyField(q, column, op, fv, o); err != nil {
+			return errors.Wrapf(err, "field %s", field.Name)
+		}
+	}
+	return nil
+}
+
+func applyField(q *Query, column, op string, fv reflect.Value, o *structOptions) error {
+	if fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface {
+		if fv.IsNil() {
+			if o.keepZero && op != "between" {
+				q.IsNull(column)
+			}
+			return nil
+		}
+		fv = fv.Elem()
+	} else if fv.IsZero() && !o.keepZero {
+		return nil
+	}
+
+	switch op {
+	case "eq":
+		q.Eq(column, fv.Interface())
+	case "not", "ne":
+		q.Not(column, fv.Interface())
+	case "in", "not_in", "nin":
+		if fv.Kind() != reflect.Slice && fv.Kind() != reflect.Array {
+			return errors.Wrapf(ErrInvalidFilter, "op %s requires slice, got %s", op, fv.Kind())
+		}
+		if fv.Len() == 0 && !o.keepZero {
+			return nil
+		}
+		if op == "in" {
+			q.In(column, fv.Interface())
+		} else {
+			q.NotIn(column, fv.Interface())
+		}
+	case "gt":
+		q.Gt(column, fv.Interface())
+	case "gte":
+		q.Gte(column, fv.Interface())
+	case "lt":
+		q.Lt(column, fv.Interface())
+	case "lte":
+		q.Lte(column, fv.Interface())
+	case "like":
+		q.Like(column, fv.Interface())
+	case "between":
+		return applyBetween(q, column, fv, o)
+	default:
+		return errors.Wrapf(ErrInvalidFilter, "unsupported op %q", op)
+	}
+	return nil
+}
+
+func applyBetween(q *Query, column string, fv reflect.Value, o *structOptions) error {
+	if (fv.Kind() != reflect.Slice && fv.Kind() != reflect.Array) || fv.Len() != 2 {
+		return errors.Wrapf(ErrInvalidFilter, "op between requires 2 elements, got %s", fv.Kind())
+	}
+
+	lower, lowerOK := elemValue(fv.Index(0), o)
+	upper, upperOK := elemValue(fv.Index(1), o)
+	switch {
+	case lowerOK && upperOK:
+		q.Between(column, lower, upper)
+	case lowerOK:
+		q.Gte(column, lower)
+	case upperOK:
+		q.Lte(column, upper)
+	}
+	return nil
+}
+
+// elemValue 解引用 v，v 为 nil 或零值（且不保留零值）时返回 false
+func elemValue(v reflect.Value, o *structOptions) (any, bool) {
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, false
+		}
+		return v.Elem().Interface(), true
+	}
+	if v.IsZero() && !o.keepZero {
+		return nil, false
+	}
+	return v.Interface(), true
+}
+
+func asPage(v reflect.Value) (Page, bool) {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, false
+	}
+	if v.Type().Implements(pageType) {
+		return v.Interface().(Page), true
+	}
+	if reflect.PointerTo(v.Type()).Implements(pageType) {
+		if v.CanAddr() {
+			return v.Addr().Interface().(Page), true
+		}
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		return ptr.Interface().(Page), true
+	}
+	return nil, false
+}
@@ -0,0 +1,115 @@
+package gormx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type PageReq struct {
+	Page     uint64
+	PageSize uint64
+	OrderBy  string
+}
+
+func (p *PageReq) GetOrderBy() string {
+	return p.OrderBy
+}
+
+func (p *PageReq) GetPage() uint64 {
+	return p.Page
+}
+
+func (p *PageReq) GetPageSize() uint64 {
+	return p.PageSize
+}
+
+type CommonFilter struct {
+	TenantID int64
+}
+
+func TestFromStruct(t *testing.T) {
+	db := newDryRunDB(t)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	type filter struct {
+		PageReq
+		CommonFilter
+		Name      string        `gormx:"op:like"`
+		Status    []string      `gormx:"op:in"`
+		MinAge    int           `gormx:"column:age;op:gte"`
+		Deleted   *bool         `gormx:"column:is_deleted"`
+		CreatedAt [2]*time.Time `gormx:"op:between"`
+		Ignored   string        `gormx:"-"`
+		internal  string
+	}
+
+	deleted := false
+	tests := []struct {
+		name   string
+		filter any
+		opts   []StructOption
+		want   string
+	}{
+		{
+			name:   "skip zero",
+			filter: filter{},
+			want:   "SELECT * FROM `users`",
+		},
+		{
+			name: "all fields",
+			filter: &filter{
+				PageReq:      PageReq{Page: 2, PageSize: 10, OrderBy: "{\"age\":\"descend\"}"},
+				CommonFilter: CommonFilter{TenantID: 1},
+				Name:         "bob",
+				Status:       []string{"a", "b"},
+				MinAge:       18,
+				Deleted:      &deleted,
+				CreatedAt:    [2]*time.Time{&from, nil},
+				Ignored:      "x",
+				internal:     "x",
+			},
+			want: "SELECT * FROM `users` WHERE `tenant_id` = 1 AND `is_deleted` = false AND `status` IN (\"a\",\"b\") " +
+				"AND `age` >= 18 AND `created_at` >= \"2024-01-01 00:00:00\" AND `name` LIKE \"%bob%\" ORDER BY `age` DESC LIMIT 10 OFFSET 10",
+		},
+		{
+			name:   "keep zero",
+			filter: filter{MinAge: 0},
+			opts:   []StructOption{WithZeroValues()},
+			want: "SELECT * FROM `users` WHERE `tenant_id` = 0 AND `status` IN (NULL) AND `age` >= 0 " +
+				"AND `name` LIKE \"%%\" AND `is_deleted` IS NULL",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := FromStruct(tt.filter, tt.opts...)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, toSQL(db, q))
+			}
+		})
+	}
+}
+
+func TestFromStruct_Invalid(t *testing.T) {
+	_, err := FromStruct(1)
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+
+	_, err = FromStruct((*struct{})(nil))
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+
+	_, err = FromStruct(struct {
+		Name string `gormx:"op:regexp"`
+	}{Name: "a"})
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+
+	_, err = FromStruct(struct {
+		Age int `gormx:"op:in"`
+	}{Age: 1})
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+
+	_, err = FromStruct(struct {
+		Age []int `gormx:"op:between"`
+	}{Age: []int{1}})
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+}
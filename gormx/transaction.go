@@ -2,22 +2,214 @@ package gormx
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
 
+	"github.com/pkg/errors"
 	"github.com/xyzbit/gpkg/ctxwrap"
 	"gorm.io/gorm"
 )
 
+// ErrTransactionExists PropagationNever 时 ctx 中已存在事务
+var ErrTransactionExists = errors.New("transaction already exists")
+
 type DBMaker interface {
 	DB(ctx context.Context) *gorm.DB
 }
 
+type dbMaker struct {
+	db *gorm.DB
+}
+
+// NewDBMaker 返回默认的 DBMaker：ctx 中存在 ctxwrap 保存的事务时使用该事务，否则使用 db，两者均绑定 ctx
+//
+// 事务结束后仍使用其 txCtx（如事务中启动的 goroutine）时，会通过 gorm 的 Logger 输出警告。
+func NewDBMaker(db *gorm.DB) DBMaker {
+	return &dbMaker{db: db}
+}
+
+func (m *dbMaker) DB(ctx context.Context) *gorm.DB {
+	return contextDB(ctx, m.db)
+}
+
+type txStateKey struct{}
+
+// txState 最外层事务的状态，保存在 txCtx 中，嵌套的事务及保存点共享同一个 txState
+type txState struct {
+	done atomic.Bool
+
+	mu    sync.Mutex
+	hooks []txHook // 按注册顺序保存的 AfterCommit、AfterRollback 回调
+}
+
+func txStateFromContext(ctx context.Context) *txState {
+	state, _ := ctx.Value(txStateKey{}).(*txState)
+	return state
+}
+
+// InTransaction ctx 中是否存在未结束的事务
+func InTransaction(ctx context.Context) bool {
+	if ctxwrap.FromGormDBContext(ctx) == nil {
+		return false
+	}
+	state := txStateFromContext(ctx)
+	return state == nil || !state.done.Load()
+}
+
+// Propagation 事务的传播方式，决定 ctx 中已存在事务时如何执行
+type Propagation int
+
+const (
+	// PropagationRequired 加入 ctx 中的事务，不存在时开启新事务，默认方式
+	PropagationRequired Propagation = iota
+	// PropagationRequiresNew 总是开启新事务，与 ctx 中的事务互不影响
+	PropagationRequiresNew
+	// PropagationNested ctx 中存在事务时创建 SAVEPOINT，失败只回滚到保存点，不存在时开启新事务
+	PropagationNested
+	// PropagationSupports 加入 ctx 中的事务，不存在时不使用事务执行
+	PropagationSupports
+	// PropagationNever 不使用事务执行，ctx 中存在事务时返回 ErrTransactionExists
+	PropagationNever
+)
+
+// TxOption 事务的选项
+type TxOption func(o *txOptions)
+
+type txOptions struct {
+	propagation Propagation
+	sqlOptions  *sql.TxOptions
+	retry       *retryOptions
+}
+
+// WithPropagation 设置事务的传播方式，默认为 PropagationRequired
+func WithPropagation(p Propagation) TxOption {
+	return func(o *txOptions) {
+		o.propagation = p
+	}
+}
+
+// WithIsolation 设置新事务的隔离级别，加入已有事务时不生效
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return func(o *txOptions) {
+		o.sql().Isolation = level
+	}
+}
+
+// WithReadOnly 将新事务设置为只读，加入已有事务时不生效
+func WithReadOnly() TxOption {
+	return func(o *txOptions) {
+		o.sql().ReadOnly = true
+	}
+}
+
+func (o *txOptions) sql() *sql.TxOptions {
+	if o.sqlOptions == nil {
+		o.sqlOptions = &sql.TxOptions{}
+	}
+	return o.sqlOptions
+}
+
+func newTxOptions(opts []TxOption) *txOptions {
+	o := &txOptions{propagation: PropagationRequired}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// savepointSeq 保存点名称的序号，避免嵌套的保存点重名
+var savepointSeq atomic.Uint64
+
 // Transaction 封装事务方法，service 层屏蔽具体 gorm 对象
 //
+// 默认加入 ctx 中由 ctxwrap 保存的事务，不存在时通过 maker 开启新事务，传播方式通过 WithPropagation 设置，
+// 死锁等错误的重试通过 TransactionWithRetry 设置。
+//
 // 注意：方法中的 context 需要使用 txCtx 作为入参
-func Transaction(ctx context.Context, maker DBMaker, fc func(txCtx context.Context) error) error {
-	db := maker.DB(ctx)
-	return db.Transaction(func(tx *gorm.DB) error {
-		ctx = ctxwrap.NewGormDBContext(ctx, tx)
+func Transaction(ctx context.Context, maker DBMaker, fc func(txCtx context.Context) error, opts ...TxOption) error {
+	o := newTxOptions(opts)
+	tx := ctxwrap.FromGormDBContext(ctx)
+	if tx != nil && !InTransaction(ctx) {
+		// ctx 中的事务已结束，视为不存在事务
+		ctx, tx = ctxwrap.NewGormDBContext(ctx, nil), nil
+	}
+
+	switch o.propagation {
+	case PropagationRequired:
+		if tx != nil {
+			return fc(ctx)
+		}
+	case PropagationRequiresNew:
+		// 清除 ctx 中的事务，避免 maker 从 ctx 中取到外层事务
+		ctx = ctxwrap.NewGormDBContext(ctx, nil)
+	case PropagationNested:
+		if tx != nil {
+			return savepoint(ctx, tx.WithContext(ctx), fc)
+		}
+	case PropagationSupports:
+		return fc(ctx)
+	case PropagationNever:
+		if tx != nil {
+			return errors.WithStack(ErrTransactionExists)
+		}
 		return fc(ctx)
+	default:
+		return errors.Errorf("unknown propagation %d", o.propagation)
+	}
+
+	db := maker.DB(ctx)
+	if o.retry == nil {
+		return begin(ctx, db, o, fc)
+	}
+	return retry(ctx, o.retry.attempts, o.retry.backoff, o.retry.retryable, func() error {
+		return begin(ctx, db, o, fc)
 	})
 }
+
+// begin 在 db 上开启新事务执行 fc
+func begin(ctx context.Context, db *gorm.DB, o *txOptions, fc func(txCtx context.Context) error) error {
+	var sqlOpts []*sql.TxOptions
+	if o.sqlOptions != nil {
+		sqlOpts = append(sqlOpts, o.sqlOptions)
+	}
+	state := &txState{}
+	committed := false
+	// fc panic 时同样执行 AfterRollback 的回调
+	defer func() {
+		state.done.Store(true)
+		state.finish(committed)
+	}()
+
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txCtx := context.WithValue(ctxwrap.NewGormDBContext(ctx, tx), txStateKey{}, state)
+		return fc(txCtx)
+	}, sqlOpts...)
+	committed = err == nil
+	return err
+}
+
+// savepoint 在 tx 中创建保存点执行 fc，fc 返回错误或 panic 时回滚到保存点
+func savepoint(ctx context.Context, tx *gorm.DB, fc func(txCtx context.Context) error) (err error) {
+	name := fmt.Sprintf("gormx_sp_%d", savepointSeq.Add(1))
+	if err := tx.SavePoint(name).Error; err != nil {
+		return err
+	}
+
+	state := txStateFromContext(ctx)
+	mark := state.mark()
+	panicked := true
+	defer func() {
+		if panicked || err != nil {
+			state.rollbackTo(mark)
+			if rerr := tx.RollbackTo(name).Error; rerr != nil && err != nil {
+				err = errors.WithMessage(err, rerr.Error())
+			}
+		}
+	}()
+
+	err = fc(ctx)
+	panicked = false
+	return err
+}
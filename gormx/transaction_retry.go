@@ -0,0 +1,84 @@
+package gormx
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// mysqlRetryableRegexp go-sql-driver/mysql 的死锁（1213）及锁等待超时（1205）错误
+var mysqlRetryableRegexp = regexp.MustCompile(`\bError (1213|1205)\b`)
+
+// RetryOption 事务重试的选项
+type RetryOption func(o *retryOptions)
+
+type retryOptions struct {
+	attempts  int
+	backoff   backoff
+	retryable func(err error) bool
+}
+
+// WithRetryable 设置判断错误是否可重试的函数，默认为 IsRetryableError
+func WithRetryable(fn func(err error) bool) RetryOption {
+	return func(o *retryOptions) {
+		if fn != nil {
+			o.retryable = fn
+		}
+	}
+}
+
+// WithBackoff 设置重试的退避间隔，第 n 次重试前等待 base*2^(n-1) 并加上随机抖动，最长不超过 maxDelay，默认为 10ms、1s
+func WithBackoff(base, maxDelay time.Duration) RetryOption {
+	return func(o *retryOptions) {
+		o.backoff = backoff{base: base, max: maxDelay}
+	}
+}
+
+// TransactionWithRetry 事务因死锁、序列化失败等可重试的错误失败时，回滚并按退避间隔重新执行整个 fc，最多执行 attempts 次
+//
+// 只在开启新事务时生效，加入 ctx 中已有的事务或创建保存点时不会重试，应由最外层事务重试；
+// 每次失败的执行都会触发其中注册的 AfterRollback 回调，等待期间 ctx 被取消时返回 ctx 的错误。
+func TransactionWithRetry(attempts int, opts ...RetryOption) TxOption {
+	ro := &retryOptions{
+		attempts:  attempts,
+		backoff:   defaultBackoff,
+		retryable: IsRetryableError,
+	}
+	for _, opt := range opts {
+		opt(ro)
+	}
+	return func(o *txOptions) {
+		o.retry = ro
+	}
+}
+
+// IsRetryableError 默认的可重试错误判断，覆盖常见数据库的死锁及序列化失败：
+//
+//	MySQL       1213 死锁、1205 锁等待超时
+//	PostgreSQL  SQLSTATE 40001 序列化失败、40P01 死锁
+//	SQLite      database is locked、database table is locked
+//	SQL Server  1205 死锁
+func IsRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		switch pgErr.SQLState() {
+		case "40001", "40P01":
+			return true
+		}
+	}
+	var mssqlErr interface{ SQLErrorNumber() int32 }
+	if errors.As(err, &mssqlErr) && mssqlErr.SQLErrorNumber() == 1205 {
+		return true
+	}
+
+	msg := err.Error()
+	return mysqlRetryableRegexp.MatchString(msg) ||
+		strings.Contains(msg, "database is locked") ||
+		strings.Contains(msg, "database table is locked")
+}
@@ -0,0 +1,124 @@
+package gormx
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "sqlstate " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+type mssqlError int32
+
+func (e mssqlError) Error() string         { return fmt.Sprintf("mssql: %d", int32(e)) }
+func (e mssqlError) SQLErrorNumber() int32 { return int32(e) }
+
+func TestIsRetryableError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("Error 1213 (40001): Deadlock found when trying to get lock"), true},
+		{errors.Wrap(errors.New("Error 1205: Lock wait timeout exceeded"), "update"), true},
+		{errors.New("Error 1062 (23000): Duplicate entry"), false},
+		{errors.Wrap(sqlStateError("40001"), "commit"), true},
+		{sqlStateError("40P01"), true},
+		{sqlStateError("23505"), false},
+		{errors.New("database is locked"), true},
+		{mssqlError(1205), true},
+		{mssqlError(2627), false},
+		{errRollback, false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, IsRetryableError(c.err), c.err)
+	}
+}
+
+func TestTransactionWithRetry(t *testing.T) {
+	db := newTxDB(t)
+	maker := NewDBMaker(db)
+	ctx := context.Background()
+	fast := WithBackoff(time.Millisecond, time.Millisecond)
+
+	var attempts, rollbacks int
+	err := Transaction(ctx, maker, func(txCtx context.Context) error {
+		attempts++
+		AfterRollback(txCtx, func() { rollbacks++ })
+		if err := createUser(txCtx, db, fmt.Sprintf("u%d", attempts)); err != nil {
+			return err
+		}
+		if attempts < 3 {
+			return sqlStateError("40001")
+		}
+		return nil
+	}, TransactionWithRetry(3, fast))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, 2, rollbacks)
+	assert.Equal(t, []string{"u3"}, userNames(t, db))
+
+	attempts = 0
+	err = Transaction(ctx, maker, func(context.Context) error {
+		attempts++
+		return sqlStateError("40P01")
+	}, TransactionWithRetry(2, fast))
+	assert.Equal(t, sqlStateError("40P01"), errors.Cause(err))
+	assert.Equal(t, 2, attempts)
+
+	attempts = 0
+	err = Transaction(ctx, maker, func(context.Context) error {
+		attempts++
+		return errRollback
+	}, TransactionWithRetry(3, fast))
+	assert.ErrorIs(t, err, errRollback)
+	assert.Equal(t, 1, attempts)
+
+	attempts = 0
+	err = Transaction(ctx, maker, func(context.Context) error {
+		attempts++
+		return errRollback
+	}, TransactionWithRetry(3, fast, WithRetryable(func(err error) bool { return errors.Is(err, errRollback) })))
+	assert.ErrorIs(t, err, errRollback)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestTransactionWithRetry_Nested(t *testing.T) {
+	db := newTxDB(t)
+	maker := NewDBMaker(db)
+
+	var outer, inner int
+	err := Transaction(context.Background(), maker, func(txCtx context.Context) error {
+		outer++
+		return Transaction(txCtx, maker, func(context.Context) error {
+			inner++
+			if outer < 2 {
+				return sqlStateError("40001")
+			}
+			return nil
+		}, TransactionWithRetry(5))
+	}, TransactionWithRetry(3, WithBackoff(time.Millisecond, time.Millisecond)))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, outer)
+	assert.Equal(t, 2, inner)
+}
+
+func TestTransactionWithRetry_Context(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var attempts int
+	err := Transaction(ctx, NewDBMaker(newTxDB(t)), func(context.Context) error {
+		attempts++
+		cancel()
+		return errors.New("database is locked")
+	}, TransactionWithRetry(3, WithBackoff(time.Hour, time.Hour)))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, attempts)
+}
@@ -0,0 +1,248 @@
+package gormx
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/xyzbit/gpkg/ctxwrap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var errRollback = errors.New("rollback")
+
+// newTxDB 使用文件数据库，RequiresNew 需要多个连接
+func newTxDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "tx.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&user{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func createUser(ctx context.Context, db *gorm.DB, name string) error {
+	return contextDB(ctx, db).Create(&user{Name: name}).Error
+}
+
+func userNames(t *testing.T, db *gorm.DB) []string {
+	var names []string
+	assert.NoError(t, db.Model(&user{}).Order("id").Pluck("name", &names).Error)
+	return names
+}
+
+func TestTransaction_Required(t *testing.T) {
+	db := newTxDB(t)
+	maker := NewDBMaker(db)
+	ctx := context.Background()
+
+	err := Transaction(ctx, maker, func(txCtx context.Context) error {
+		outer := ctxwrap.FromGormDBContext(txCtx)
+		assert.NotNil(t, outer)
+		if err := createUser(txCtx, db, "a"); err != nil {
+			return err
+		}
+		return Transaction(txCtx, maker, func(innerCtx context.Context) error {
+			assert.Same(t, outer, ctxwrap.FromGormDBContext(innerCtx))
+			return createUser(innerCtx, db, "b")
+		})
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, userNames(t, db))
+
+	err = Transaction(ctx, maker, func(txCtx context.Context) error {
+		if err := createUser(txCtx, db, "c"); err != nil {
+			return err
+		}
+		return Transaction(txCtx, maker, func(innerCtx context.Context) error {
+			if err := createUser(innerCtx, db, "d"); err != nil {
+				return err
+			}
+			return errRollback
+		})
+	})
+	assert.ErrorIs(t, err, errRollback)
+	assert.Equal(t, []string{"a", "b"}, userNames(t, db))
+}
+
+func TestTransaction_RequiresNew(t *testing.T) {
+	db := newTxDB(t)
+	maker := NewDBMaker(db)
+
+	err := Transaction(context.Background(), maker, func(txCtx context.Context) error {
+		outer := ctxwrap.FromGormDBContext(txCtx)
+		err := Transaction(txCtx, maker, func(innerCtx context.Context) error {
+			inner := ctxwrap.FromGormDBContext(innerCtx)
+			assert.NotNil(t, inner)
+			assert.NotSame(t, outer, inner)
+			return createUser(innerCtx, db, "audit")
+		}, WithPropagation(PropagationRequiresNew))
+		if err != nil {
+			return err
+		}
+		if err := createUser(txCtx, db, "a"); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+	assert.Equal(t, []string{"audit"}, userNames(t, db))
+}
+
+func TestTransaction_Nested(t *testing.T) {
+	db := newTxDB(t)
+	maker := NewDBMaker(db)
+	ctx := context.Background()
+	nested := WithPropagation(PropagationNested)
+
+	err := Transaction(ctx, maker, func(txCtx context.Context) error {
+		if err := createUser(txCtx, db, "a"); err != nil {
+			return err
+		}
+		err := Transaction(txCtx, maker, func(innerCtx context.Context) error {
+			if err := createUser(innerCtx, db, "b"); err != nil {
+				return err
+			}
+			return errRollback
+		}, nested)
+		assert.ErrorIs(t, err, errRollback)
+
+		return Transaction(txCtx, maker, func(innerCtx context.Context) error {
+			return createUser(innerCtx, db, "c")
+		}, nested)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "c"}, userNames(t, db))
+
+	// 不存在事务时开启新事务
+	err = Transaction(ctx, maker, func(txCtx context.Context) error {
+		assert.NotNil(t, ctxwrap.FromGormDBContext(txCtx))
+		return createUser(txCtx, db, "d")
+	}, nested)
+	assert.NoError(t, err)
+
+	assert.Panics(t, func() {
+		_ = Transaction(ctx, maker, func(txCtx context.Context) error {
+			if err := createUser(txCtx, db, "e"); err != nil {
+				return err
+			}
+			return Transaction(txCtx, maker, func(innerCtx context.Context) error {
+				panic("boom")
+			}, nested)
+		})
+	})
+	assert.Equal(t, []string{"a", "c", "d"}, userNames(t, db))
+}
+
+func TestTransaction_SupportsAndNever(t *testing.T) {
+	db := newTxDB(t)
+	maker := NewDBMaker(db)
+	ctx := context.Background()
+
+	err := Transaction(ctx, maker, func(txCtx context.Context) error {
+		assert.Nil(t, ctxwrap.FromGormDBContext(txCtx))
+		return nil
+	}, WithPropagation(PropagationSupports))
+	assert.NoError(t, err)
+
+	err = Transaction(ctx, maker, func(txCtx context.Context) error {
+		assert.Nil(t, ctxwrap.FromGormDBContext(txCtx))
+		return nil
+	}, WithPropagation(PropagationNever))
+	assert.NoError(t, err)
+
+	err = Transaction(ctx, maker, func(txCtx context.Context) error {
+		outer := ctxwrap.FromGormDBContext(txCtx)
+		err := Transaction(txCtx, maker, func(innerCtx context.Context) error {
+			assert.Same(t, outer, ctxwrap.FromGormDBContext(innerCtx))
+			return nil
+		}, WithPropagation(PropagationSupports))
+		if err != nil {
+			return err
+		}
+		return Transaction(txCtx, maker, func(context.Context) error {
+			t.Fatal("should not run")
+			return nil
+		}, WithPropagation(PropagationNever))
+	})
+	assert.ErrorIs(t, err, ErrTransactionExists)
+}
+
+func TestTxOptions(t *testing.T) {
+	o := newTxOptions(nil)
+	assert.Equal(t, PropagationRequired, o.propagation)
+	assert.Nil(t, o.sqlOptions)
+
+	o = newTxOptions([]TxOption{WithIsolation(sql.LevelSerializable), WithReadOnly(), WithPropagation(PropagationRequiresNew)})
+	assert.Equal(t, PropagationRequiresNew, o.propagation)
+	assert.Equal(t, &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true}, o.sqlOptions)
+
+	err := Transaction(context.Background(), NewDBMaker(newTxDB(t)), func(txCtx context.Context) error {
+		return nil
+	}, WithPropagation(Propagation(-1)))
+	assert.Error(t, err)
+}
+
+type warnLogger struct {
+	logger.Interface
+	warnings []string
+}
+
+func (l *warnLogger) LogMode(logger.LogLevel) logger.Interface {
+	return l
+}
+
+func (l *warnLogger) Warn(_ context.Context, msg string, _ ...any) {
+	l.warnings = append(l.warnings, msg)
+}
+
+func TestNewDBMaker(t *testing.T) {
+	db := newTxDB(t)
+	log := &warnLogger{Interface: logger.Discard}
+	db.Logger = log
+	maker := NewDBMaker(db)
+
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "v")
+	assert.False(t, InTransaction(ctx))
+	assert.Equal(t, ctx, maker.DB(ctx).Statement.Context)
+
+	var leaked context.Context
+	err := Transaction(ctx, maker, func(txCtx context.Context) error {
+		leaked = txCtx
+		assert.True(t, InTransaction(txCtx))
+		tx := maker.DB(txCtx)
+		assert.Equal(t, txCtx, tx.Statement.Context)
+		assert.Equal(t, ctxwrap.FromGormDBContext(txCtx).Statement.ConnPool, tx.Statement.ConnPool)
+		return tx.Create(&user{Name: "a"}).Error
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, log.warnings)
+
+	// 事务结束后继续使用 txCtx
+	assert.False(t, InTransaction(leaked))
+	err = maker.DB(leaked).Create(&user{Name: "b"}).Error
+	assert.ErrorIs(t, err, sql.ErrTxDone)
+	assert.Len(t, log.warnings, 1)
+
+	// 在已结束的 txCtx 上开启事务时使用新事务
+	err = Transaction(leaked, maker, func(txCtx context.Context) error {
+		assert.True(t, InTransaction(txCtx))
+		return createUser(txCtx, db, "c")
+	})
+	assert.NoError(t, err)
+	assert.Len(t, log.warnings, 1)
+	assert.Equal(t, []string{"a", "c"}, userNames(t, db))
+}
@@ -0,0 +1,101 @@
+package gormx
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// VersionColumn 乐观锁使用的版本号列
+const VersionColumn = "version"
+
+// ErrConcurrentModification 乐观锁更新时记录已被其他请求修改或已被删除
+var ErrConcurrentModification = errors.New("concurrent modification")
+
+// UpdateWithVersion 以乐观锁更新 model 对应的记录，model 为带主键及版本号列的结构体指针
+//
+// 生成 UPDATE ... SET ..., version = 当前版本号 + 1 WHERE id = ? AND version = 当前版本号，
+// 没有匹配到记录时返回 ErrConcurrentModification 且 model 保持不变，成功时 model 同步为更新后的值。
+func UpdateWithVersion(ctx context.Context, db *gorm.DB, model any, updates map[string]any) error {
+	rv := reflect.ValueOf(model)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.WithStack(ErrMissingModel)
+	}
+	rv = rv.Elem()
+
+	tx := contextDB(ctx, db)
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+	field := stmt.Schema.LookUpField(VersionColumn)
+	if field == nil {
+		return errors.Wrap(ErrUnknownColumn, VersionColumn)
+	}
+	if len(stmt.Schema.PrimaryFields) == 0 {
+		return errors.WithStack(ErrMissingWhere)
+	}
+	for _, pk := range stmt.Schema.PrimaryFields {
+		if _, zero := pk.ValueOf(ctx, rv); zero {
+			return errors.Wrapf(ErrMissingWhere, "primary key %s is zero", pk.DBName)
+		}
+	}
+
+	version, _ := field.ValueOf(ctx, rv)
+	next, err := nextVersion(version)
+	if err != nil {
+		return err
+	}
+	values := make(map[string]any, len(updates)+1)
+	for k, v := range updates {
+		values[k] = v
+	}
+	values[field.DBName] = next
+
+	// gorm 会将更新的值回写到 model 上，冲突时需要还原
+	origin := reflect.New(rv.Type()).Elem()
+	origin.Set(rv)
+
+	res := tx.Model(model).
+		Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: version}).
+		Updates(values)
+	if res.Error != nil || res.RowsAffected == 0 {
+		rv.Set(origin)
+	}
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errors.WithStack(ErrConcurrentModification)
+	}
+	return nil
+}
+
+// IsConcurrentModification err 是否为乐观锁冲突
+func IsConcurrentModification(err error) bool {
+	return errors.Is(err, ErrConcurrentModification)
+}
+
+// RetryOnConflict 执行 fn，返回 ErrConcurrentModification 时按退避间隔重新执行，最多执行 attempts 次
+//
+// fn 需要在每次执行时重新读取记录，以拿到最新的版本号。
+func RetryOnConflict(ctx context.Context, attempts int, fn func(ctx context.Context) error) error {
+	return retry(ctx, attempts, defaultBackoff, IsConcurrentModification, func() error {
+		return fn(ctx)
+	})
+}
+
+func nextVersion(version any) (any, error) {
+	v := reflect.ValueOf(version)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() + 1, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() + 1, nil
+	default:
+		return nil, errors.Errorf("unsupported version type %T", version)
+	}
+}
@@ -0,0 +1,121 @@
+package gormx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type account struct {
+	ID      int64
+	Balance int
+	Version int
+}
+
+func newAccountDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := db.AutoMigrate(&account{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&account{ID: 1, Balance: 100, Version: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestUpdateWithVersion(t *testing.T) {
+	db := newAccountDB(t)
+	ctx := context.Background()
+
+	a, b := &account{}, &account{}
+	assert.NoError(t, db.First(a, 1).Error)
+	assert.NoError(t, db.First(b, 1).Error)
+
+	assert.NoError(t, UpdateWithVersion(ctx, db, a, map[string]any{"balance": 80}))
+	assert.Equal(t, &account{ID: 1, Balance: 80, Version: 2}, a)
+
+	err := UpdateWithVersion(ctx, db, b, map[string]any{"balance": 50})
+	assert.True(t, IsConcurrentModification(err), err)
+	assert.Equal(t, &account{ID: 1, Balance: 100, Version: 1}, b)
+
+	got := &account{}
+	assert.NoError(t, db.First(got, 1).Error)
+	assert.Equal(t, &account{ID: 1, Balance: 80, Version: 2}, got)
+
+	err = UpdateWithVersion(ctx, db, &account{ID: 2, Version: 1}, map[string]any{"balance": 1})
+	assert.True(t, IsConcurrentModification(err), err)
+
+	err = UpdateWithVersion(ctx, db, &account{Version: 2}, map[string]any{"balance": 1})
+	assert.True(t, errors.Is(err, ErrMissingWhere), err)
+
+	err = UpdateWithVersion(ctx, db, account{ID: 1}, map[string]any{"balance": 1})
+	assert.True(t, errors.Is(err, ErrMissingModel), err)
+
+	err = UpdateWithVersion(ctx, newSQLiteDB(t), &user{ID: 1}, map[string]any{"age": 1})
+	assert.True(t, errors.Is(err, ErrUnknownColumn), err)
+}
+
+func TestRetryOnConflict(t *testing.T) {
+	db := newAccountDB(t)
+	ctx := context.Background()
+
+	stale := &account{}
+	assert.NoError(t, db.First(stale, 1).Error)
+	assert.NoError(t, UpdateWithVersion(ctx, db, &account{ID: 1, Balance: 90, Version: 1}, map[string]any{"balance": 90}))
+
+	var calls int
+	err := RetryOnConflict(ctx, 3, func(ctx context.Context) error {
+		calls++
+		a := stale
+		if calls > 1 {
+			a = &account{}
+			if err := db.First(a, 1).Error; err != nil {
+				return err
+			}
+		}
+		return UpdateWithVersion(ctx, db, a, map[string]any{"balance": a.Balance - 10})
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	got := &account{}
+	assert.NoError(t, db.First(got, 1).Error)
+	assert.Equal(t, &account{ID: 1, Balance: 80, Version: 3}, got)
+
+	calls = 0
+	err = RetryOnConflict(ctx, 3, func(ctx context.Context) error {
+		calls++
+		return ErrConcurrentModification
+	})
+	assert.True(t, IsConcurrentModification(err))
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = RetryOnConflict(ctx, 3, func(ctx context.Context) error {
+		calls++
+		return errors.New("boom")
+	})
+	assert.EqualError(t, err, "boom")
+	assert.Equal(t, 1, calls)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = RetryOnConflict(cancelled, 3, func(ctx context.Context) error {
+		return ErrConcurrentModification
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
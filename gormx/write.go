@@ -0,0 +1,153 @@
+package gormx
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultWriteBatchSize = 500
+
+var (
+	// ErrMissingWhere UpdateByQuery、DeleteByQuery 没有任何 WHERE 条件
+	ErrMissingWhere = errors.New("missing where conditions")
+	// ErrMissingModel 无法确定写入的表，需要通过 Query.Model 或 db.Model 指定
+	ErrMissingModel = errors.New("missing model")
+)
+
+// WriteOption 批量写入的选项
+type WriteOption func(o *writeOptions)
+
+type writeOptions struct {
+	batchSize       int
+	conflictColumns []string
+	updateColumns   []string
+	doNothing       bool
+}
+
+// WithBatchSize 每批写入的行数，默认 500
+func WithBatchSize(size int) WriteOption {
+	return func(o *writeOptions) {
+		if size > 0 {
+			o.batchSize = size
+		}
+	}
+}
+
+// WithConflictColumns Upsert 判断冲突的列，默认为主键；MySQL 根据表上的唯一索引判断冲突，会忽略该选项
+func WithConflictColumns(columns ...string) WriteOption {
+	return func(o *writeOptions) {
+		o.conflictColumns = columns
+	}
+}
+
+// WithUpdateColumns Upsert 冲突时更新的列，默认更新除主键外的全部列
+func WithUpdateColumns(columns ...string) WriteOption {
+	return func(o *writeOptions) {
+		o.updateColumns = columns
+	}
+}
+
+// WithDoNothing Upsert 冲突时忽略该行
+func WithDoNothing() WriteOption {
+	return func(o *writeOptions) {
+		o.doNothing = true
+	}
+}
+
+func newWriteOptions(opts []WriteOption) *writeOptions {
+	o := &writeOptions{batchSize: defaultWriteBatchSize}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// CreateInBatches 分批插入 rows，每批的行数通过 WithBatchSize 设置
+func CreateInBatches[T any](ctx context.Context, db *gorm.DB, rows []T, opts ...WriteOption) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	o := newWriteOptions(opts)
+	return contextDB(ctx, db).CreateInBatches(&rows, o.batchSize).Error
+}
+
+// Upsert 分批插入 rows，冲突时按 WithUpdateColumns 更新或按 WithDoNothing 忽略
+//
+// 冲突处理由 gorm 的 dialector 生成，MySQL 为 ON DUPLICATE KEY UPDATE，PostgreSQL、SQLite 为 ON CONFLICT。
+func Upsert[T any](ctx context.Context, db *gorm.DB, rows []T, opts ...WriteOption) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	o := newWriteOptions(opts)
+	onConflict := clause.OnConflict{}
+	for _, c := range o.conflictColumns {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: c})
+	}
+	switch {
+	case o.doNothing:
+		onConflict.DoNothing = true
+	case len(o.updateColumns) > 0:
+		onConflict.DoUpdates = clause.AssignmentColumns(o.updateColumns)
+	default:
+		onConflict.UpdateAll = true
+	}
+
+	return contextDB(ctx, db).Clauses(onConflict).CreateInBatches(&rows, o.batchSize).Error
+}
+
+// UpsertProcessor 返回用于 batch.NewBatch 的批处理函数，将收集到的数据通过 Upsert 写入，失败时调用 onError
+func UpsertProcessor[T any](db *gorm.DB, onError func(rows []T, err error), opts ...WriteOption) func([]T) {
+	return func(rows []T) {
+		if err := Upsert(context.Background(), db, rows, opts...); err != nil && onError != nil {
+			onError(rows, err)
+		}
+	}
+}
+
+// UpdateByQuery 更新满足 q 的记录，返回更新的行数，updates 为 map[string]any 或结构体
+//
+// q 必须包含 WHERE 条件，否则返回 ErrMissingWhere 而不会执行。
+func UpdateByQuery(ctx context.Context, db *gorm.DB, q *Query, updates any) (int64, error) {
+	tx, err := writeDB(ctx, db, q)
+	if err != nil {
+		return 0, err
+	}
+
+	res := tx.Updates(updates)
+	return res.RowsAffected, res.Error
+}
+
+// DeleteByQuery 删除满足 q 的记录，返回删除的行数，模型包含 gorm.DeletedAt 时为软删除
+//
+// q 必须包含 WHERE 条件，否则返回 ErrMissingWhere 而不会执行。
+func DeleteByQuery(ctx context.Context, db *gorm.DB, q *Query) (int64, error) {
+	tx, err := writeDB(ctx, db, q)
+	if err != nil {
+		return 0, err
+	}
+
+	res := tx.Delete(tx.Statement.Model)
+	return res.RowsAffected, res.Error
+}
+
+// writeDB 将 q 应用到 db 上，并检查是否指定了模型及 WHERE 条件
+func writeDB(ctx context.Context, db *gorm.DB, q *Query) (*gorm.DB, error) {
+	tx := q.WithDB(contextDB(ctx, db))
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	if tx.Statement.Model == nil {
+		return nil, errors.WithStack(ErrMissingModel)
+	}
+
+	where, ok := tx.Statement.Clauses["WHERE"].Expression.(clause.Where)
+	if !ok || len(where.Exprs) == 0 {
+		return nil, errors.WithStack(ErrMissingWhere)
+	}
+	return tx, nil
+}
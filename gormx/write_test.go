@@ -0,0 +1,136 @@
+package gormx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/xyzbit/gpkg/batch"
+	"gorm.io/gorm"
+)
+
+func TestCreateInBatches(t *testing.T) {
+	db := newSQLiteDB(t)
+	ctx := context.Background()
+
+	var statements int
+	_ = db.Callback().Create().After("gorm:create").Register("count", func(*gorm.DB) { statements++ })
+
+	rows := []user{{Name: "d", Age: 1}, {Name: "e", Age: 2}, {Name: "f", Age: 3}}
+	assert.NoError(t, CreateInBatches(ctx, db, rows, WithBatchSize(2)))
+	assert.Equal(t, 2, statements)
+	assert.NoError(t, CreateInBatches[user](ctx, db, nil))
+
+	total, err := Count[user](ctx, db, NewQuery())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), total)
+}
+
+func TestUpsert(t *testing.T) {
+	db := newSQLiteDB(t)
+	ctx := context.Background()
+
+	rows := []user{{ID: 1, Name: "Alice2", Age: 19}, {ID: 4, Name: "Dave", Age: 40}}
+	assert.NoError(t, Upsert(ctx, db, rows, WithUpdateColumns("age")))
+
+	got, err := Find[user](ctx, db, NewQuery().In("id", []int{1, 4}).OrderBy("id"))
+	assert.NoError(t, err)
+	assert.Equal(t, []user{{ID: 1, Name: "Alice", Age: 19}, {ID: 4, Name: "Dave", Age: 40}}, got)
+
+	assert.NoError(t, Upsert(ctx, db, []user{{ID: 1, Name: "Alice3", Age: 20}}, WithConflictColumns("id")))
+	assert.NoError(t, Upsert(ctx, db, []user{{ID: 4, Name: "Eve", Age: 50}}, WithDoNothing()))
+
+	got, err = Find[user](ctx, db, NewQuery().In("id", []int{1, 4}).OrderBy("id"))
+	assert.NoError(t, err)
+	assert.Equal(t, []user{{ID: 1, Name: "Alice3", Age: 20}, {ID: 4, Name: "Dave", Age: 40}}, got)
+}
+
+func TestUpsert_SQL(t *testing.T) {
+	db := newPostgresDryRunDB(t)
+
+	var sql string
+	_ = db.Callback().Create().After("gorm:create").Register("capture", func(tx *gorm.DB) {
+		sql = tx.Dialector.Explain(tx.Statement.SQL.String(), tx.Statement.Vars...)
+	})
+
+	err := Upsert(context.Background(), db.Session(&gorm.Session{SkipDefaultTransaction: true}), []user{{ID: 1, Name: "a"}}, WithConflictColumns("id"), WithUpdateColumns("name"))
+	assert.NoError(t, err)
+	assert.Equal(t, `INSERT INTO "users" ("name","age","id") VALUES ('a',0,1) ON CONFLICT ("id") DO UPDATE SET "name"="excluded"."name" RETURNING "id"`, sql)
+}
+
+func TestUpsertProcessor(t *testing.T) {
+	db := newSQLiteDB(t)
+	stop := make(chan struct{})
+	defer close(stop)
+
+	processed := make(chan int, 10)
+	failed := make(chan error, 10)
+	upsert := UpsertProcessor(db, func(rows []user, err error) { failed <- err }, WithUpdateColumns("age"))
+	b := batch.NewBatch(func(rows []user) {
+		upsert(rows)
+		if len(rows) > 0 {
+			processed <- len(rows)
+		}
+	}, batch.WithBatchSize[user](2), batch.WithDone[user](stop))
+
+	b.SendData(user{ID: 2, Age: 26})
+	b.SendData(user{ID: 5, Name: "Frank", Age: 60})
+	select {
+	case n := <-processed:
+		assert.Equal(t, 2, n)
+	case <-time.After(time.Second):
+		t.Fatal("batch not processed")
+	}
+	assert.Empty(t, failed)
+
+	got, err := Find[user](context.Background(), db, NewQuery().In("id", []int{2, 5}).OrderBy("id"))
+	assert.NoError(t, err)
+	assert.Equal(t, []int{26, 60}, ages(got))
+}
+
+func TestUpdateByQuery(t *testing.T) {
+	db := newSQLiteDB(t)
+	ctx := context.Background()
+
+	n, err := UpdateByQuery(ctx, db, NewQuery().Model(&user{}).Gte("age", 25), map[string]any{"age": gorm.Expr("age + 1")})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	n, err = UpdateByQuery(ctx, db.Model(&user{}), NewQuery().Eq("name", "Alice"), user{Name: "alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	got, err := Find[user](ctx, db, NewQuery().OrderBy("id"))
+	assert.NoError(t, err)
+	assert.Equal(t, []user{{ID: 1, Name: "alice", Age: 18}, {ID: 2, Name: "bob", Age: 26}, {ID: 3, Name: "Carol", Age: 33}}, got)
+
+	_, err = UpdateByQuery(ctx, db, NewQuery().Model(&user{}), map[string]any{"age": 1})
+	assert.True(t, errors.Is(err, ErrMissingWhere), err)
+
+	_, err = UpdateByQuery(ctx, db, NewQuery().Model(&user{}).AndGroup(func(sub *Query) {}).Limit(1), map[string]any{"age": 1})
+	assert.True(t, errors.Is(err, ErrMissingWhere), err)
+
+	_, err = UpdateByQuery(ctx, db, NewQuery().Eq("id", 1), map[string]any{"age": 1})
+	assert.True(t, errors.Is(err, ErrMissingModel), err)
+
+	_, err = UpdateByQuery(ctx, db, NewQuery().Model(&user{}).Eq("unknown", 1), map[string]any{"age": 1})
+	assert.True(t, errors.Is(err, ErrUnknownColumn), err)
+}
+
+func TestDeleteByQuery(t *testing.T) {
+	db := newSQLiteDB(t)
+	ctx := context.Background()
+
+	_, err := DeleteByQuery(ctx, db, NewQuery().Model(&user{}))
+	assert.True(t, errors.Is(err, ErrMissingWhere), err)
+
+	n, err := DeleteByQuery(ctx, db, NewQuery().Model(&user{}).Lt("age", 30))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	got, err := Find[user](ctx, db, NewQuery())
+	assert.NoError(t, err)
+	assert.Equal(t, []int{32}, ages(got))
+}
@@ -0,0 +1,200 @@
+package limiter
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultInitialLimit     = 20
+	defaultMinLimit         = 1
+	defaultMaxLimit         = 1000
+	defaultBackoffRatio     = 0.9
+	defaultLatencyTolerance = 2.0
+	defaultRTTWindow        = 10 * time.Second
+)
+
+var ErrOverloaded = errors.New("concurrency limit exceeded")
+
+type AdaptiveOption func(a *AdaptiveLimiter)
+
+// WithInitialLimit 初始并发上限
+func WithInitialLimit(limit int) AdaptiveOption {
+	return func(a *AdaptiveLimiter) {
+		if limit > 0 {
+			a.limit = float64(limit)
+		}
+	}
+}
+
+// WithLimitRange 并发上限的调整范围
+func WithLimitRange(minLimit, maxLimit int) AdaptiveOption {
+	return func(a *AdaptiveLimiter) {
+		if minLimit > 0 && maxLimit >= minLimit {
+			a.minLimit = float64(minLimit)
+			a.maxLimit = float64(maxLimit)
+		}
+	}
+}
+
+// WithBackoffRatio 过载时并发上限的乘性缩减系数，取值 (0, 1)
+func WithBackoffRatio(ratio float64) AdaptiveOption {
+	return func(a *AdaptiveLimiter) {
+		if ratio > 0 && ratio < 1 {
+			a.backoffRatio = ratio
+		}
+	}
+}
+
+// WithLatencyTolerance 延迟超过最小延迟的 tolerance 倍时视为过载
+func WithLatencyTolerance(tolerance float64) AdaptiveOption {
+	return func(a *AdaptiveLimiter) {
+		if tolerance >= 1 {
+			a.tolerance = tolerance
+		}
+	}
+}
+
+// WithRTTWindow 最小延迟的统计窗口，窗口结束后以新窗口内的最小延迟为准
+func WithRTTWindow(window time.Duration) AdaptiveOption {
+	return func(a *AdaptiveLimiter) {
+		if window > 0 {
+			a.rttWindow = window
+		}
+	}
+}
+
+// WithClock 自定义时钟，便于测试
+func WithClock(now func() time.Time) AdaptiveOption {
+	return func(a *AdaptiveLimiter) {
+		if now != nil {
+			a.now = now
+		}
+	}
+}
+
+// AdaptiveLimiter 自适应并发限流器（AIMD），根据请求的延迟及成败动态调整并发上限
+//
+// 请求成功且延迟正常时并发上限加性增长，请求失败或延迟超过最小延迟的 tolerance 倍时乘性缩减，
+// 在途请求达到上限时直接拒绝，避免过载时请求堆积。
+type AdaptiveLimiter struct {
+	lock         sync.Mutex
+	limit        float64
+	minLimit     float64
+	maxLimit     float64
+	inflight     int
+	backoffRatio float64
+	tolerance    float64
+
+	minRTT       time.Duration // 上一个窗口的最小延迟
+	windowRTT    time.Duration // 当前窗口的最小延迟
+	windowStart  time.Time
+	rttWindow    time.Duration
+	lastDecrease time.Time
+
+	now func() time.Time
+}
+
+func NewAdaptiveLimiter(opts ...AdaptiveOption) *AdaptiveLimiter {
+	a := &AdaptiveLimiter{
+		limit:        defaultInitialLimit,
+		minLimit:     defaultMinLimit,
+		maxLimit:     defaultMaxLimit,
+		backoffRatio: defaultBackoffRatio,
+		tolerance:    defaultLatencyTolerance,
+		rttWindow:    defaultRTTWindow,
+		now:          time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	a.limit = math.Min(math.Max(a.limit, a.minLimit), a.maxLimit)
+	a.windowStart = a.now()
+	return a
+}
+
+// Acquire 申请执行一个请求，在途请求达到并发上限时返回 ErrOverloaded
+//
+// 请求结束后必须调用返回的 done 上报结果，success 为 false 表示请求失败（如超时、下游过载）。
+func (a *AdaptiveLimiter) Acquire() (done func(success bool), err error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.inflight >= int(a.limit) {
+		return nil, ErrOverloaded
+	}
+	a.inflight++
+
+	start := a.now()
+	var once sync.Once
+	return func(success bool) {
+		once.Do(func() {
+			a.release(start, success)
+		})
+	}, nil
+}
+
+// Limit 当前的并发上限
+func (a *AdaptiveLimiter) Limit() int {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return int(a.limit)
+}
+
+// Inflight 当前在途的请求数量
+func (a *AdaptiveLimiter) Inflight() int {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.inflight
+}
+
+func (a *AdaptiveLimiter) release(start time.Time, success bool) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	now := a.now()
+	rtt := now.Sub(start)
+	inflight := a.inflight
+	a.inflight--
+
+	// 失败请求的延迟不能反映下游的处理能力（如快速失败），不参与最小延迟的统计
+	if success {
+		a.updateRTT(now, rtt)
+	}
+
+	overloaded := !success || (a.minRTT > 0 && float64(rtt) > float64(a.minRTT)*a.tolerance)
+	if overloaded {
+		// 同一个 RTT 内只缩减一次，避免一批慢请求把并发上限打到底
+		if now.Sub(a.lastDecrease) >= a.minRTT {
+			a.limit = math.Max(a.limit*a.backoffRatio, a.minLimit)
+			a.lastDecrease = now
+		}
+		return
+	}
+
+	// 只有在途请求接近上限时才增长，避免流量低时上限无限膨胀
+	if float64(inflight)*2 >= a.limit {
+		a.limit = math.Min(a.limit+1, a.maxLimit)
+	}
+}
+
+// updateRTT 更新最小延迟，调用方需持有锁
+func (a *AdaptiveLimiter) updateRTT(now time.Time, rtt time.Duration) {
+	if a.windowRTT == 0 || rtt < a.windowRTT {
+		a.windowRTT = rtt
+	}
+	if a.minRTT == 0 || a.windowRTT < a.minRTT {
+		a.minRTT = a.windowRTT
+	}
+
+	if now.Sub(a.windowStart) >= a.rttWindow {
+		a.minRTT = a.windowRTT
+		a.windowRTT = 0
+		a.windowStart = now
+	}
+}
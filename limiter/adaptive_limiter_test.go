@@ -0,0 +1,95 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptiveLimiter_Acquire(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	a := NewAdaptiveLimiter(WithInitialLimit(2), WithClock(clock.Now))
+
+	done1, err := a.Acquire()
+	assert.NoError(t, err)
+	done2, err := a.Acquire()
+	assert.NoError(t, err)
+	_, err = a.Acquire()
+	assert.ErrorIs(t, err, ErrOverloaded)
+	assert.Equal(t, 2, a.Inflight())
+
+	clock.Advance(10 * time.Millisecond)
+	done1(true)
+	done1(true) // 重复调用无副作用
+	done2(true)
+	assert.Equal(t, 0, a.Inflight())
+	// 第二个请求结束时在途请求已不足上限的一半，不再增长
+	assert.Equal(t, 3, a.Limit())
+}
+
+func TestAdaptiveLimiter_AIMD(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	a := NewAdaptiveLimiter(WithInitialLimit(10), WithLimitRange(2, 20), WithClock(clock.Now))
+
+	run := func(n int, latency time.Duration, success bool) {
+		dones := make([]func(bool), 0, n)
+		for i := 0; i < n; i++ {
+			done, err := a.Acquire()
+			if err != nil {
+				break
+			}
+			dones = append(dones, done)
+		}
+		clock.Advance(latency)
+		for _, done := range dones {
+			done(success)
+		}
+	}
+
+	// 延迟正常且在途请求饱和时加性增长，直到上限
+	for i := 0; i < 10; i++ {
+		run(a.Limit(), 10*time.Millisecond, true)
+	}
+	assert.Equal(t, 20, a.Limit())
+
+	// 延迟升高时乘性缩减，同一个 RTT 内只缩减一次
+	run(a.Limit(), 50*time.Millisecond, true)
+	assert.Equal(t, 18, a.Limit())
+
+	// 请求失败时同样缩减，并且不低于下限
+	for i := 0; i < 50; i++ {
+		run(a.Limit(), 10*time.Millisecond, false)
+	}
+	assert.Equal(t, 2, a.Limit())
+
+	// 在途请求不少于上限的一半时才增长：上限为 2 时 1 个在途请求仍增长到 3，之后流量较低不再增长
+	run(1, 10*time.Millisecond, true)
+	run(1, 10*time.Millisecond, true)
+	assert.Equal(t, 3, a.Limit())
+}
+
+func TestAdaptiveLimiter_FastFailure(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	a := NewAdaptiveLimiter(WithInitialLimit(20), WithLimitRange(1, 20), WithClock(clock.Now))
+
+	run := func(latency time.Duration, success bool) {
+		done, err := a.Acquire()
+		if !assert.NoError(t, err) {
+			return
+		}
+		clock.Advance(latency)
+		done(success)
+	}
+
+	run(10*time.Millisecond, true)
+	// 快速失败的请求只缩减一次，不会拉低最小延迟
+	run(100*time.Microsecond, false)
+	assert.Equal(t, 18, a.Limit())
+
+	for i := 0; i < 50; i++ {
+		run(10*time.Millisecond, true)
+	}
+	assert.Equal(t, 18, a.Limit())
+	assert.Equal(t, 10*time.Millisecond, a.minRTT)
+}
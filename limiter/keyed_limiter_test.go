@@ -0,0 +1,89 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyedLimiter_AllowN(t *testing.T) {
+	s := miniredis.RunT(t)
+	cli := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	limits := NewStaticLimits(2)
+	limits.Set("vip", 5)
+	kl, err := NewKeyedLimiter(cli, "", limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	allowCount := func(key string) int {
+		count := 0
+		for i := 0; i < 10; i++ {
+			if allow, _ := kl.Allow(ctx, key); allow {
+				count++
+			}
+		}
+		return count
+	}
+
+	assert.Equal(t, 2, allowCount("free"))
+	assert.Equal(t, 5, allowCount("vip"))
+	// 新的配额在新 key 上立即生效
+	limits.SetDefault(3)
+	assert.Equal(t, 3, allowCount("another"))
+
+	limits.Set("zero", 0)
+	_, err = kl.Allow(ctx, "zero")
+	assert.ErrorIs(t, err, ErrQPSNonPositive)
+}
+
+func TestKeyedLimiter_ProviderError(t *testing.T) {
+	s := miniredis.RunT(t)
+	cli := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	errNoPlan := errors.New("no plan")
+	kl, err := NewKeyedLimiter(cli, "", LimitProviderFunc(func(ctx context.Context, key string) (int, error) {
+		return 0, errNoPlan
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = kl.AllowN(context.Background(), "tenant", 1)
+	assert.ErrorIs(t, err, errNoPlan)
+}
+
+func TestKeyedLimiter_NonPositiveN(t *testing.T) {
+	s := miniredis.RunT(t)
+	cli := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	kl, err := NewKeyedLimiter(cli, "", NewStaticLimits(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for _, n := range []int{0, -100} {
+		allow, err := kl.AllowN(ctx, "tenant", n)
+		assert.False(t, allow)
+		assert.ErrorIs(t, err, ErrNNonPositive)
+	}
+
+	// 桶内令牌未被抬高
+	allow, _ := kl.AllowN(ctx, "tenant", 2)
+	assert.True(t, allow)
+	allow, _ = kl.Allow(ctx, "tenant")
+	assert.False(t, allow)
+}
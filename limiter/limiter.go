@@ -0,0 +1,80 @@
+package limiter
+
+import (
+	"context"
+	"time"
+)
+
+// Limiter 限流器，本地限流器与分布式限流器均实现该接口，调用方可以无缝切换
+type Limiter interface {
+	Allow(ctx context.Context) (bool, error)
+	AllowN(ctx context.Context, n int) (bool, error)
+	Wait(ctx context.Context) error
+}
+
+// Result 窗口限流的判定结果，可用于设置 X-RateLimit-* 响应头
+type Result struct {
+	Allowed   bool      // 是否放行
+	Limit     int       // 窗口内的总配额
+	Remaining int       // 窗口内剩余配额
+	ResetAt   time.Time // 配额恢复的时间
+}
+
+var (
+	_ Limiter = (*TokenLimiter)(nil)
+	_ Limiter = (*PeriodLimiter)(nil)
+	_ Limiter = (*SlidingWindowLimiter)(nil)
+	_ Limiter = (*LocalTokenLimiter)(nil)
+	_ Limiter = (*LocalLeakyLimiter)(nil)
+)
+
+// parseResult 解析 lua 脚本返回的 {allowed, remaining, resetMillis}
+func parseResult(raw any, limit int, now time.Time) (*Result, bool) {
+	values, ok := raw.([]any)
+	if !ok || len(values) != 3 {
+		return nil, false
+	}
+
+	allowed, ok1 := values[0].(int64)
+	remaining, ok2 := values[1].(int64)
+	resetMillis, ok3 := values[2].(int64)
+	if !ok1 || !ok2 || !ok3 {
+		return nil, false
+	}
+
+	return &Result{
+		Allowed:   allowed == 1,
+		Limit:     limit,
+		Remaining: int(max(remaining, 0)),
+		ResetAt:   now.Add(time.Duration(max(resetMillis, 0)) * time.Millisecond),
+	}, true
+}
+
+// wait 循环调用 try 直到其返回的等待时间为 0，期间响应 ctx 的取消
+func wait(ctx context.Context, try func() (time.Duration, error)) error {
+	for {
+		delay, err := try()
+		if err != nil {
+			return err
+		}
+		if delay <= 0 {
+			return nil
+		}
+
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,172 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrBurstNonPositive = errors.New("burst is non-positive")
+	ErrBucketOverflow   = errors.New("bucket overflow")
+	ErrNExceedsBurst    = errors.New("n exceeds burst")
+)
+
+// LocalTokenLimiter 进程内令牌桶限流器，桶容量为 burst，每秒产生 qps 个令牌
+type LocalTokenLimiter struct {
+	lock   sync.Mutex
+	qps    float64
+	burst  float64
+	tokens float64
+	lastTs time.Time
+	now    func() time.Time
+}
+
+func NewLocalTokenLimiter(qps, burst int) (*LocalTokenLimiter, error) {
+	if qps <= 0 {
+		return nil, ErrQPSNonPositive
+	}
+	if burst <= 0 {
+		return nil, ErrBurstNonPositive
+	}
+
+	return &LocalTokenLimiter{
+		qps:    float64(qps),
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}, nil
+}
+
+func (l *LocalTokenLimiter) Allow(ctx context.Context) (bool, error) {
+	return l.AllowN(ctx, 1)
+}
+
+// AllowN 获取 n 个令牌，n 超过 burst 时永远无法满足，返回 ErrNExceedsBurst
+func (l *LocalTokenLimiter) AllowN(_ context.Context, n int) (bool, error) {
+	if n <= 0 {
+		return false, ErrNNonPositive
+	}
+	if float64(n) > l.burst {
+		return false, ErrNExceedsBurst
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.refill()
+	if l.tokens < float64(n) {
+		return false, nil
+	}
+	l.tokens -= float64(n)
+	return true, nil
+}
+
+// Wait 预占 1 个令牌并等待令牌产生，ctx 结束时归还预占的令牌
+func (l *LocalTokenLimiter) Wait(ctx context.Context) error {
+	l.lock.Lock()
+	l.refill()
+	l.tokens--
+	delay := time.Duration(-l.tokens / l.qps * float64(time.Second))
+	l.lock.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	if err := sleep(ctx, delay); err != nil {
+		l.lock.Lock()
+		// 等待期间桶可能已被补满，归还后不能超过容量
+		l.refill()
+		l.tokens = min(l.tokens+1, l.burst)
+		l.lock.Unlock()
+		return err
+	}
+	return nil
+}
+
+// refill 按经过的时间补充令牌，调用方需持有锁
+func (l *LocalTokenLimiter) refill() {
+	now := l.now()
+	if !l.lastTs.IsZero() {
+		elapsed := now.Sub(l.lastTs).Seconds()
+		if elapsed > 0 {
+			l.tokens = min(l.tokens+elapsed*l.qps, l.burst)
+		}
+	}
+	l.lastTs = now
+}
+
+// LocalLeakyLimiter 进程内漏桶限流器，请求以 1/qps 的固定间隔匀速流出，不允许突发
+//
+// Allow 仅在无需排队时放行；Wait 在桶内排队等待，排队的请求超过 capacity 时返回 ErrBucketOverflow。
+type LocalLeakyLimiter struct {
+	lock     sync.Mutex
+	interval time.Duration
+	capacity int
+	next     time.Time // 下一个请求最早的流出时间
+	now      func() time.Time
+}
+
+func NewLocalLeakyLimiter(qps, capacity int) (*LocalLeakyLimiter, error) {
+	if qps <= 0 {
+		return nil, ErrQPSNonPositive
+	}
+	if capacity <= 0 {
+		return nil, ErrBurstNonPositive
+	}
+
+	return &LocalLeakyLimiter{
+		interval: time.Second / time.Duration(qps),
+		capacity: capacity,
+		now:      time.Now,
+	}, nil
+}
+
+func (l *LocalLeakyLimiter) Allow(ctx context.Context) (bool, error) {
+	return l.AllowN(ctx, 1)
+}
+
+func (l *LocalLeakyLimiter) AllowN(_ context.Context, n int) (bool, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	if now.Before(l.next) {
+		return false, nil
+	}
+	l.next = now.Add(time.Duration(n) * l.interval)
+	return true, nil
+}
+
+// Wait 在桶内排队，直到轮到当前请求流出或 ctx 结束
+func (l *LocalLeakyLimiter) Wait(ctx context.Context) error {
+	l.lock.Lock()
+	now := l.now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	delay := start.Sub(now)
+	if delay > time.Duration(l.capacity)*l.interval {
+		l.lock.Unlock()
+		return ErrBucketOverflow
+	}
+	l.next = start.Add(l.interval)
+	l.lock.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	if err := sleep(ctx, delay); err != nil {
+		l.lock.Lock()
+		// 仍是队尾时归还占用的流出时间
+		if l.next.Equal(start.Add(l.interval)) {
+			l.next = start
+		}
+		l.lock.Unlock()
+		return err
+	}
+	return nil
+}
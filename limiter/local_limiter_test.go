@@ -0,0 +1,177 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.now = f.now.Add(d)
+}
+
+func TestLocalTokenLimiter_AllowN(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	l, err := NewLocalTokenLimiter(10, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.now = clock.Now
+
+	ctx := context.Background()
+	allow, _ := l.AllowN(ctx, 5)
+	assert.True(t, allow)
+	allow, _ = l.Allow(ctx)
+	assert.False(t, allow)
+
+	// 100ms 产生 1 个令牌
+	clock.Advance(100 * time.Millisecond)
+	allow, _ = l.Allow(ctx)
+	assert.True(t, allow)
+	allow, _ = l.Allow(ctx)
+	assert.False(t, allow)
+
+	// 令牌数量不超过 burst
+	clock.Advance(time.Hour)
+	allow, _ = l.AllowN(ctx, 5)
+	assert.True(t, allow)
+}
+
+func TestLocalTokenLimiter_InvalidN(t *testing.T) {
+	l, err := NewLocalTokenLimiter(10, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for _, n := range []int{0, -100} {
+		allow, err := l.AllowN(ctx, n)
+		assert.False(t, allow)
+		assert.ErrorIs(t, err, ErrNNonPositive)
+	}
+	allow, err := l.AllowN(ctx, 6)
+	assert.False(t, allow)
+	assert.ErrorIs(t, err, ErrNExceedsBurst)
+
+	// 非法的 n 不影响桶内令牌
+	allow, _ = l.AllowN(ctx, 5)
+	assert.True(t, allow)
+	allow, _ = l.Allow(ctx)
+	assert.False(t, allow)
+}
+
+func TestLocalTokenLimiter_Wait(t *testing.T) {
+	l, err := NewLocalTokenLimiter(100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, l.Wait(ctx))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 35*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	assert.NoError(t, l.Wait(context.Background()))
+	assert.ErrorIs(t, l.Wait(ctx), context.DeadlineExceeded)
+}
+
+func TestLocalTokenLimiter_WaitCancelDuringRefill(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	l, err := NewLocalTokenLimiter(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.now = clock.Now
+
+	allow, _ := l.Allow(context.Background())
+	assert.True(t, allow)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- l.Wait(ctx)
+	}()
+
+	// 等待 Wait 预占令牌
+	assert.Eventually(t, func() bool {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		return l.tokens < 0
+	}, time.Second, time.Millisecond)
+
+	// Wait 等待期间桶被补满
+	l.lock.Lock()
+	clock.Advance(10 * time.Second)
+	l.refill()
+	assert.Equal(t, float64(1), l.tokens)
+	l.lock.Unlock()
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	l.lock.Lock()
+	assert.Equal(t, float64(1), l.tokens)
+	l.lock.Unlock()
+	allow, _ = l.AllowN(context.Background(), 2)
+	assert.False(t, allow)
+}
+
+func TestLocalLeakyLimiter_AllowN(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	l, err := NewLocalLeakyLimiter(10, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.now = clock.Now
+
+	ctx := context.Background()
+	allow, _ := l.Allow(ctx)
+	assert.True(t, allow)
+	// 漏桶不允许突发
+	allow, _ = l.Allow(ctx)
+	assert.False(t, allow)
+
+	clock.Advance(100 * time.Millisecond)
+	allow, _ = l.AllowN(ctx, 2)
+	assert.True(t, allow)
+	clock.Advance(100 * time.Millisecond)
+	allow, _ = l.Allow(ctx)
+	assert.False(t, allow)
+	clock.Advance(100 * time.Millisecond)
+	allow, _ = l.Allow(ctx)
+	assert.True(t, allow)
+}
+
+func TestLocalLeakyLimiter_Wait(t *testing.T) {
+	l, err := NewLocalLeakyLimiter(100, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, l.Wait(ctx))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	// 排队超过容量时溢出
+	clock := &fakeClock{now: time.Now()}
+	l.now = clock.Now
+	l.next = clock.now.Add(time.Second)
+	assert.ErrorIs(t, l.Wait(ctx), ErrBucketOverflow)
+}
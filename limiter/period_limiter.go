@@ -0,0 +1,103 @@
+package limiter
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrQuotaNonPositive  = errors.New("quota is non-positive")
+	ErrPeriodNonPositive = errors.New("period is non-positive")
+	ErrUnexpectedReply   = errors.New("unexpected lua reply")
+	ErrNNonPositive      = errors.New("n is non-positive")
+)
+
+// PeriodLimiter 固定窗口限流器，每个窗口内最多放行 quota 个请求
+//
+// 窗口从第一次请求开始计时，窗口结束后配额一次性恢复。
+type PeriodLimiter struct {
+	luaSha string
+	key    string
+
+	redisClient *redis.Client
+	period      time.Duration
+	quota       int
+}
+
+func NewPeriodLimiter(cli *redis.Client, key string, period time.Duration, quota int) (*PeriodLimiter, error) {
+	if quota <= 0 {
+		return nil, ErrQuotaNonPositive
+	}
+	if period.Milliseconds() <= 0 {
+		return nil, ErrPeriodNonPositive
+	}
+
+	ctx := context.Background()
+	err := cli.Ping(ctx).Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if key == "" {
+		key = defaultLimiterKey
+	}
+	key += "_period_limiter"
+
+	return &PeriodLimiter{
+		luaSha:      cli.ScriptLoad(ctx, periodLimiterLuaScript).Val(),
+		key:         key,
+		redisClient: cli,
+		period:      period,
+		quota:       quota,
+	}, nil
+}
+
+func (p *PeriodLimiter) Allow(ctx context.Context) (bool, error) {
+	return p.AllowN(ctx, 1)
+}
+
+func (p *PeriodLimiter) AllowN(ctx context.Context, n int) (bool, error) {
+	res, err := p.TakeN(ctx, n)
+	if err != nil {
+		return false, err
+	}
+	return res.Allowed, nil
+}
+
+// Take 获取 1 个配额
+func (p *PeriodLimiter) Take(ctx context.Context) (*Result, error) {
+	return p.TakeN(ctx, 1)
+}
+
+// Wait 阻塞直到获取到 1 个配额或 ctx 结束
+func (p *PeriodLimiter) Wait(ctx context.Context) error {
+	return wait(ctx, func() (time.Duration, error) {
+		res, err := p.TakeN(ctx, 1)
+		if err != nil || res.Allowed {
+			return 0, err
+		}
+		return max(time.Until(res.ResetAt), time.Millisecond), nil
+	})
+}
+
+// TakeN 获取 n 个配额，并返回当前窗口的剩余配额及重置时间
+func (p *PeriodLimiter) TakeN(ctx context.Context, n int) (*Result, error) {
+	if n <= 0 {
+		return nil, ErrNNonPositive
+	}
+
+	now := time.Now()
+	raw, err := p.redisClient.EvalSha(ctx, p.luaSha, []string{p.key}, p.quota, n, p.period.Milliseconds()).Result()
+	if err != nil {
+		return nil, errors.Wrap(ErrExecLua, err.Error())
+	}
+
+	res, ok := parseResult(raw, p.quota, now)
+	if !ok {
+		return nil, ErrUnexpectedReply
+	}
+	return res, nil
+}
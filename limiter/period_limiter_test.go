@@ -0,0 +1,71 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeriodLimiter_TakeN(t *testing.T) {
+	s := miniredis.RunT(t)
+	cli := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	pl, err := NewPeriodLimiter(cli, "", time.Second, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		res, err := pl.Take(ctx)
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 5, res.Limit)
+		assert.Equal(t, 4-i, res.Remaining)
+		assert.WithinRange(t, res.ResetAt, start, start.Add(2*time.Second))
+	}
+
+	res, err := pl.Take(ctx)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	// 窗口结束后配额恢复
+	s.FastForward(time.Second)
+	allow, err := pl.AllowN(ctx, 5)
+	assert.NoError(t, err)
+	assert.True(t, allow)
+}
+
+func TestPeriodLimiter_Invalid(t *testing.T) {
+	s := miniredis.RunT(t)
+	cli := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	_, err := NewPeriodLimiter(cli, "", time.Second, 0)
+	assert.ErrorIs(t, err, ErrQuotaNonPositive)
+	_, err = NewPeriodLimiter(cli, "", 0, 10)
+	assert.ErrorIs(t, err, ErrPeriodNonPositive)
+
+	pl, err := NewPeriodLimiter(cli, "", time.Second, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, n := range []int{0, -3} {
+		_, err = pl.TakeN(ctx, n)
+		assert.ErrorIs(t, err, ErrNNonPositive)
+	}
+	res, err := pl.TakeN(ctx, 5)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+}
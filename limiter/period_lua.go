@@ -0,0 +1,27 @@
+package limiter
+
+const (
+	periodLimiterLuaScript = `
+local limit = tonumber(ARGV[1]) -- 窗口内的总配额
+local need = tonumber(ARGV[2])
+local window = tonumber(ARGV[3]) -- 窗口大小，单位毫秒
+
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+if current + need > limit then
+    local ttl = redis.call('PTTL', KEYS[1])
+    if ttl < 0 then
+        ttl = window
+    end
+    return {0, limit - current, ttl}
+end
+
+current = redis.call('INCRBY', KEYS[1], need)
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < 0 then -- 窗口内的第一次请求，开启新窗口
+    redis.call('PEXPIRE', KEYS[1], window)
+    ttl = window
+end
+
+return {1, limit - current, ttl}
+`
+)
@@ -0,0 +1,100 @@
+package limiter
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// SlidingWindowLimiter 滑动窗口限流器，任意 window 时长内最多放行 quota 个请求
+//
+// 基于 redis sorted set 记录窗口内每个请求的时间，配额随最早的请求滑出窗口逐个恢复。
+type SlidingWindowLimiter struct {
+	luaSha string
+	key    string
+
+	redisClient *redis.Client
+	window      time.Duration
+	quota       int
+}
+
+func NewSlidingWindowLimiter(cli *redis.Client, key string, window time.Duration, quota int) (*SlidingWindowLimiter, error) {
+	if quota <= 0 {
+		return nil, ErrQuotaNonPositive
+	}
+	if window.Milliseconds() <= 0 {
+		return nil, ErrPeriodNonPositive
+	}
+
+	ctx := context.Background()
+	err := cli.Ping(ctx).Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if key == "" {
+		key = defaultLimiterKey
+	}
+	key += "_sliding_window_limiter"
+
+	return &SlidingWindowLimiter{
+		luaSha:      cli.ScriptLoad(ctx, slidingWindowLimiterLuaScript).Val(),
+		key:         key,
+		redisClient: cli,
+		window:      window,
+		quota:       quota,
+	}, nil
+}
+
+func (s *SlidingWindowLimiter) Allow(ctx context.Context) (bool, error) {
+	return s.AllowN(ctx, 1)
+}
+
+func (s *SlidingWindowLimiter) AllowN(ctx context.Context, n int) (bool, error) {
+	res, err := s.TakeN(ctx, n)
+	if err != nil {
+		return false, err
+	}
+	return res.Allowed, nil
+}
+
+// Take 获取 1 个配额
+func (s *SlidingWindowLimiter) Take(ctx context.Context) (*Result, error) {
+	return s.TakeN(ctx, 1)
+}
+
+// Wait 阻塞直到获取到 1 个配额或 ctx 结束
+func (s *SlidingWindowLimiter) Wait(ctx context.Context) error {
+	return wait(ctx, func() (time.Duration, error) {
+		res, err := s.TakeN(ctx, 1)
+		if err != nil || res.Allowed {
+			return 0, err
+		}
+		return max(time.Until(res.ResetAt), time.Millisecond), nil
+	})
+}
+
+// TakeN 获取 n 个配额，并返回窗口内的剩余配额及下一个配额恢复的时间
+func (s *SlidingWindowLimiter) TakeN(ctx context.Context, n int) (*Result, error) {
+	if n <= 0 {
+		return nil, ErrNNonPositive
+	}
+
+	now := time.Now()
+	member := strconv.FormatInt(now.UnixNano(), 36) + "-" + strconv.FormatInt(rand.Int63(), 36)
+
+	raw, err := s.redisClient.EvalSha(ctx, s.luaSha, []string{s.key}, s.quota, n, s.window.Milliseconds(), now.UnixMilli(), member).Result()
+	if err != nil {
+		return nil, errors.Wrap(ErrExecLua, err.Error())
+	}
+
+	res, ok := parseResult(raw, s.quota, now)
+	if !ok {
+		return nil, ErrUnexpectedReply
+	}
+	return res, nil
+}
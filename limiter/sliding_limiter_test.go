@@ -0,0 +1,75 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlidingWindowLimiter_TakeN(t *testing.T) {
+	s := miniredis.RunT(t)
+	cli := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	window := 200 * time.Millisecond
+	sl, err := NewSlidingWindowLimiter(cli, "", window, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	res, err := sl.TakeN(ctx, 2)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+
+	time.Sleep(window / 2)
+	res, err = sl.Take(ctx)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	res, err = sl.Take(ctx)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	// 最早的两个请求滑出窗口的时间不会超过一个窗口
+	assert.WithinRange(t, res.ResetAt, time.Now(), time.Now().Add(window))
+
+	// 前两个请求滑出窗口后恢复两个配额
+	time.Sleep(time.Until(res.ResetAt) + 10*time.Millisecond)
+	res, err = sl.TakeN(ctx, 2)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	allow, err := sl.Allow(ctx)
+	assert.NoError(t, err)
+	assert.False(t, allow)
+}
+
+func TestSlidingWindowLimiter_NonPositiveN(t *testing.T) {
+	s := miniredis.RunT(t)
+	cli := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	sl, err := NewSlidingWindowLimiter(cli, "", time.Second, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for _, n := range []int{0, -3} {
+		_, err = sl.TakeN(ctx, n)
+		assert.ErrorIs(t, err, ErrNNonPositive)
+	}
+	res, err := sl.TakeN(ctx, 3)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+}
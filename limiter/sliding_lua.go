@@ -0,0 +1,34 @@
+package limiter
+
+const (
+	slidingWindowLimiterLuaScript = `
+local limit = tonumber(ARGV[1]) -- 窗口内的总配额
+local need = tonumber(ARGV[2])
+local window = tonumber(ARGV[3]) -- 窗口大小，单位毫秒
+local nowTs = tonumber(ARGV[4]) -- 当前时间，单位毫秒
+local member = ARGV[5] -- 本次请求的唯一标识
+
+-- 清理已经滑出窗口的请求
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', nowTs - window)
+
+local current = redis.call('ZCARD', KEYS[1])
+local allowed = 0
+if current + need <= limit then
+    for i = 1, need do
+        redis.call('ZADD', KEYS[1], nowTs, member .. ':' .. i)
+    end
+    redis.call('PEXPIRE', KEYS[1], window)
+    current = current + need
+    allowed = 1
+end
+
+-- 最早的请求滑出窗口时恢复配额
+local reset = window
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+if #oldest > 0 then
+    reset = tonumber(oldest[2]) + window - nowTs
+end
+
+return {allowed, limit - current, reset}
+`
+)
@@ -3,6 +3,7 @@ package limiter
 import (
 	"context"
 	"math"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
@@ -23,7 +24,7 @@ type TokenLimiter struct {
 	key    string
 
 	redisClient *redis.Client
-	QPS         int
+	qps         atomic.Int64
 }
 
 func NewTokenLimiter(cli *redis.Client, key string, qps int) (*TokenLimiter, error) {
@@ -38,28 +39,69 @@ func NewTokenLimiter(cli *redis.Client, key string, qps int) (*TokenLimiter, err
 	}
 	key += "_token_limiter"
 
-	return &TokenLimiter{
+	tl := &TokenLimiter{
 		luaSha:      cli.ScriptLoad(ctx, tokenLimiterLuaScript).Val(),
 		key:         key,
 		redisClient: cli,
-		QPS:         qps,
-	}, nil
+	}
+	tl.qps.Store(int64(qps))
+	return tl, nil
+}
+
+// QPS 当前每秒产生的令牌数量
+func (t *TokenLimiter) QPS() int {
+	return int(t.qps.Load())
+}
+
+// SetQPS 运行时原子地调整每秒产生的令牌数量
+func (t *TokenLimiter) SetQPS(qps int) {
+	t.qps.Store(int64(qps))
+}
+
+func (t *TokenLimiter) Allow(ctx context.Context) (bool, error) {
+	return t.AllowN(ctx, 1)
 }
 
-func (t *TokenLimiter) Allow() (bool, error) {
-	return t.AllowN(1)
+func (t *TokenLimiter) AllowN(ctx context.Context, n int) (bool, error) {
+	return takeTokens(ctx, t.redisClient, t.luaSha, t.key, n, t.QPS())
+}
+
+// Wait 阻塞直到获取到 1 个令牌或 ctx 结束
+func (t *TokenLimiter) Wait(ctx context.Context) error {
+	return wait(ctx, func() (time.Duration, error) {
+		return t.DelayN(ctx, 1)
+	})
+}
+
+// DelayN 尝试获取 n 个令牌，获取失败时返回建议的重试间隔
+func (t *TokenLimiter) DelayN(ctx context.Context, n int) (time.Duration, error) {
+	qps := t.QPS()
+	allow, err := takeTokens(ctx, t.redisClient, t.luaSha, t.key, n, qps)
+	if err != nil {
+		return time.Duration(math.MaxInt64), err
+	}
+	if allow {
+		return 0, nil
+	}
+
+	return time.Duration(float64(n)/float64(qps)*1e9) * time.Nanosecond, nil
 }
 
-func (t *TokenLimiter) AllowN(n int) (bool, error) {
-	if t.QPS <= 0 {
+// takeTokens 执行令牌桶脚本，从 key 对应的桶中获取 n 个令牌
+func takeTokens(ctx context.Context, cli *redis.Client, luaSha, key string, n, qps int) (bool, error) {
+	if qps <= 0 {
 		return false, ErrQPSNonPositive
 	}
+	// 负数的 n 会使脚本向桶中加入令牌
+	if n <= 0 {
+		return false, ErrNNonPositive
+	}
 
-	maxToken := t.QPS
+	maxToken := qps
 	needToken := n
-	tokenPerSec := t.QPS
+	tokenPerSec := qps
 
-	raw, err := t.redisClient.EvalSha(context.Background(), t.luaSha, []string{t.key}, needToken, maxToken, tokenPerSec, time.Now().Unix()).Result()
+	raw, err := cli.EvalSha(ctx, luaSha, []string{key}, needToken, maxToken, tokenPerSec, time.Now().Unix()).Result()
 	if err != nil {
 		return false, errors.Wrap(ErrExecLua, err.Error())
 	}
@@ -71,15 +113,3 @@ func (t *TokenLimiter) AllowN(n int) (bool, error) {
 
 	return true, nil
 }
-
-func (t *TokenLimiter) DelayN(n int) (time.Duration, error) {
-	allow, err := t.AllowN(n)
-	if err != nil {
-		return time.Duration(math.MaxInt64), err
-	}
-	if allow {
-		return 0, nil
-	}
-
-	return time.Duration(float64(n)/float64(t.QPS)*1e9) * time.Nanosecond, nil
-}
@@ -1,6 +1,7 @@
 package limiter
 
 import (
+	"context"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -35,7 +36,7 @@ func TestTokenLimiter_Allow(t *testing.T) {
 		for i := 0; i < loopCount; i++ {
 			go func(idx int) {
 				defer wg.Done()
-				if allow, _ := tl.AllowN(allowCount); allow {
+				if allow, _ := tl.AllowN(context.Background(), allowCount); allow {
 					reqCount.Add(int32(allowCount))
 					return
 				}
@@ -50,7 +51,7 @@ func TestTokenLimiter_Allow(t *testing.T) {
 		for i := 1000; i < loopCount+1000; i++ {
 			go func(idx int) {
 				defer wg.Done()
-				if allow, _ := tl.AllowN(allowCount); allow {
+				if allow, _ := tl.AllowN(context.Background(), allowCount); allow {
 					reqCount.Add(int32(allowCount))
 					return
 				}
@@ -61,10 +62,10 @@ func TestTokenLimiter_Allow(t *testing.T) {
 	wg.Wait()
 
 	t.Logf("since: %v, reqcount: %v", time.Since(start), reqCount.Load())
-	assert.Equal(t, tl.QPS, int(reqCount.Load()))
+	assert.Equal(t, tl.QPS(), int(reqCount.Load()))
 
 	// gotEndTime := time.Now()
-	// wantEndTime := start.Add(time.Duration(reqCount.Load()/int32(tl.QPS)) * time.Second)
+	// wantEndTime := start.Add(time.Duration(reqCount.Load()/int32(tl.QPS())) * time.Second)
 	// mistake := 10 * time.Millisecond // 误差容忍
 	// assert.WithinRange(t, gotEndTime, wantEndTime.Add(-10*mistake), wantEndTime.Add(10*mistake))
 }
@@ -91,7 +92,7 @@ func TestTokenLimiter_DelayN(t *testing.T) {
 		go func(idx int) {
 			defer wg.Done()
 			for {
-				delay, err := tl.DelayN(1)
+				delay, err := tl.DelayN(context.Background(), 1)
 				if err != nil {
 					t.Error(err)
 				}
@@ -107,7 +108,7 @@ func TestTokenLimiter_DelayN(t *testing.T) {
 	wg.Wait()
 
 	gotEndTime := time.Now()
-	wantEndTime := start.Add(time.Duration(reqCount.Load()/int32(tl.QPS)) * time.Second)
+	wantEndTime := start.Add(time.Duration(reqCount.Load()/int32(tl.QPS())) * time.Second)
 	mistake := 1 * time.Second // 误差容忍, 随着请求时间的增长, 误差会变大
 	t.Logf("real mistake:%v, req count:%d", wantEndTime.Sub(gotEndTime), reqCount.Load())
 	assert.WithinRange(t, gotEndTime, wantEndTime.Add(-1*mistake), wantEndTime.Add(1*mistake))
@@ -125,7 +126,7 @@ func BenchmarkTokenLimiter_AllowN(b *testing.B) {
 
 	for i := 0; i < b.N; i++ {
 		go func(idx int) {
-			if allow, _ := tl.AllowN(10); allow {
+			if allow, _ := tl.AllowN(context.Background(), 10); allow {
 				// fmt.Printf("No.%d execute success  ---at time:[%s] \n", idx, time.Now().Format("2006-01-02 15:04:05"))
 				return
 			}